@@ -69,3 +69,46 @@ Create the name of the service account to use
   {{- .Release.Namespace -}}
 {{- end -}}
 {{- end -}}
+
+{{/* Build an attribute mapper definition */}}
+{{- define "k8-ldap-configmap.attributeMapper" -}}
+{{- $opts := list (printf "name=%s" .name) -}}
+{{- range $key := list "entity" "key" "value" "output" "configmap" -}}
+{{- if hasKey $ $key -}}
+{{- $opts = append $opts (printf "%s=%s" $key (get $ $key)) -}}
+{{- end -}}
+{{- end -}}
+{{- join "," $opts -}}
+{{- end -}}
+
+{{/* Comma separated namespaces of generated ConfigMaps */}}
+{{- define "k8-ldap-configmap.configMapNamespaces" -}}
+{{- $namespaces := list (.Values.namespaceConfigMap | default .Release.Namespace) -}}
+{{- range .Values.namespacesConfigMap -}}
+{{- $namespaces = append $namespaces . -}}
+{{- end -}}
+{{- join "," (uniq $namespaces) -}}
+{{- end -}}
+
+{{/* Comma separated key=value pairs of a map */}}
+{{- define "k8-ldap-configmap.keyValues" -}}
+{{- $pairs := list -}}
+{{- range $key, $value := . -}}
+{{- $pairs = append $pairs (printf "%s=%s" $key $value) -}}
+{{- end -}}
+{{- join "," $pairs -}}
+{{- end -}}
+
+{{/* ConfigMap name of a mapper, takes a dict with the root context and the mapper name */}}
+{{- define "k8-ldap-configmap.configMapName" -}}
+{{- $root := .root -}}
+{{- $mapper := splitList ":" .mapper | first -}}
+{{- $name := printf "%s-map" $mapper -}}
+{{- range (concat $root.Values.attributeMappers $root.Values.templateMappers) -}}
+{{- if and (eq .name $mapper) .configmap -}}
+{{- $name = .configmap -}}
+{{- end -}}
+{{- end -}}
+{{- $name = get $root.Values.configMapNames $mapper | default $name -}}
+{{- printf "%s%s%s" $root.Values.configMapPrefix $name $root.Values.configMapSuffix -}}
+{{- end -}}
@@ -15,12 +15,17 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"log/slog"
 	"net/http"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -29,6 +34,7 @@ import (
 	localldap "github.com/OSC/k8-ldap-configmap/internal/ldap"
 	"github.com/OSC/k8-ldap-configmap/internal/mapper"
 	"github.com/OSC/k8-ldap-configmap/internal/metrics"
+	"github.com/OSC/k8-ldap-configmap/internal/sink"
 	"github.com/OSC/k8-ldap-configmap/internal/utils"
 	"github.com/alecthomas/kingpin/v2"
 	ldap "github.com/go-ldap/ldap/v3"
@@ -36,9 +42,20 @@ import (
 	"github.com/prometheus/common/promslog"
 	"github.com/prometheus/common/version"
 	"golang.org/x/sync/errgroup"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/validation/path"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
@@ -47,8 +64,18 @@ import (
 )
 
 const (
-	appName     = "k8-ldap-configmap"
-	metricsPath = "/metrics"
+	appName        = "k8-ldap-configmap"
+	metricsPath    = "/metrics"
+	fieldManager   = "k8-ldap-configmap"
+	managedByLabel = "app.kubernetes.io/managed-by"
+	mapperLabel    = "k8-ldap-configmap.osc.edu/mapper"
+	instanceLabel  = "k8-ldap-configmap.osc.edu/instance"
+	// dataHashAnnotation stores the hash of the data and metadata last written
+	dataHashAnnotation     = "k8-ldap-configmap.osc.edu/data-hash"
+	lastSyncAnnotation     = "k8-ldap-configmap.osc.edu/last-sync-time"
+	ldapURLAnnotation      = "k8-ldap-configmap.osc.edu/ldap-url"
+	userEntriesAnnotation  = "k8-ldap-configmap.osc.edu/user-entries"
+	groupEntriesAnnotation = "k8-ldap-configmap.osc.edu/group-entries"
 )
 
 var (
@@ -62,22 +89,74 @@ var (
 	ldapBindPassword      = kingpin.Flag("ldap-bind-password", "LDAP Bind Password").Envar("LDAP_BIND_PASSWORD").String()
 	ldapGroupFilter       = kingpin.Flag("ldap-group-filter", "LDAP group filter").Default("(objectClass=posixGroup)").Envar("LDAP_GROUP_FILTER").String()
 	ldapUserFilter        = kingpin.Flag("ldap-user-filter", "LDAP user filter").Default("(objectClass=posixAccount)").Envar("LDAP_USER_FILTER").String()
+	ldapNetgroupBaseDN    = kingpin.Flag("ldap-netgroup-base-dn", "LDAP Netgroup Base DN, required by netgroup mappers").Envar("LDAP_NETGROUP_BASE_DN").String()
+	ldapNetgroupFilter    = kingpin.Flag("ldap-netgroup-filter", "LDAP netgroup filter").Default("(objectClass=nisNetgroup)").Envar("LDAP_NETGROUP_FILTER").String()
+	ldapSudoersBaseDN     = kingpin.Flag("ldap-sudoers-base-dn", "LDAP sudoers Base DN, required by the user-sudo mapper").Envar("LDAP_SUDOERS_BASE_DN").String()
+	ldapSudoersFilter     = kingpin.Flag("ldap-sudoers-filter", "LDAP sudoers filter").Default("(objectClass=sudoRole)").Envar("LDAP_SUDOERS_FILTER").String()
+	ldapAutomountBaseDN   = kingpin.Flag("ldap-automount-base-dn", "LDAP automount Base DN, required by automount maps").Envar("LDAP_AUTOMOUNT_BASE_DN").String()
 	ldapPagedSearch       = kingpin.Flag("ldap-paged-search", "Enable LDAP paged searching").Default("false").Envar("LDAP_PAGED_SEARCH").Bool()
 	ldapPagedSearchSize   = kingpin.Flag("ldap-paged-search-size", " LDAP paged search size").Default("1000").Envar("LDAP_PAGED_SEARCH_SIZE").Int()
 	ldapMemberScheme      = kingpin.Flag("ldap-member-scheme", "Scheme used to define group members, either memberof, member or memberuid").Default("memberof").Envar("LDAP_MEMBER_SCHEME").String()
+	ldapNestedGroups      = kingpin.Flag("ldap-nested-groups", "Resolve membership of nested groups, only supported with member and memberof member schemes").Default("false").Envar("LDAP_NESTED_GROUPS").Bool()
+	ldapNestedGroupsDepth = kingpin.Flag("ldap-nested-groups-depth", "Maximum levels of group nesting to resolve").Default("10").Envar("LDAP_NESTED_GROUPS_DEPTH").Int()
+	ldapNestedGroupsChain = kingpin.Flag("ldap-nested-groups-in-chain", "Resolve nested groups with the Active Directory LDAP_MATCHING_RULE_IN_CHAIN matching rule, requires one search per group").Default("false").Envar("LDAP_NESTED_GROUPS_IN_CHAIN").Bool()
 	ldapUserAttrMap       = kingpin.Flag("ldap-user-attr-map", "Attribute map for users").Default(config.DefaultUserAttrMap).Envar("LDAP_USER_ATTR_MAP").String()
 	ldapGroupAttrMap      = kingpin.Flag("ldap-group-attr-map", "Attribute map for groups").Default(config.DefaultGroupAttrMap).Envar("LDAP_GROUP_ATTR_MAP").String()
-	mappersArg            = kingpin.Flag("mappers", "Comma separated list of mappers to generate.").Default("user-uid,user-gid").Envar("MAPPERS").String()
+	mappersArg            = kingpin.Flag("mappers", "Comma separated list of mappers to generate, a mapper can be named with <name>:<mapper>").Default("user-uid,user-gid").Envar("MAPPERS").String()
 	mappersGroupFilter    = kingpin.Flag("mappers-group-filter", "Comma separated mappers filters map for groups").Default("").Envar("MAPPERS_GROUP_FILTER").String()
+	attributeMappersArg   = kingpin.Flag("attribute-mapper", "Attribute mapper definition, may be repeated. Format: name=<name>,entity=<user|group>,key=<attr>,value=<attr>[+<attr>],output=<single|json>,configmap=<name>").Envar("ATTRIBUTE_MAPPERS").Strings()
+	templateMappersArg    = kingpin.Flag("template-mapper", "Template mapper definition as JSON, may be repeated. Format: {\"name\":\"<name>\",\"key\":\"<template>\",\"value\":\"<template>\",\"configmap\":\"<name>\"}").Envar("TEMPLATE_MAPPERS").Strings()
+	automountMaps         = kingpin.Flag("automount-maps", "Comma separated list of automount maps to generate").Default("").Envar("AUTOMOUNT_MAPS").String()
+	automountUserMaps     = kingpin.Flag("automount-user-maps", "Comma separated list of automount maps that also have keys for each user").Default("").Envar("AUTOMOUNT_USER_MAPS").String()
+	secretMappers         = kingpin.Flag("secret-mappers", "Comma separated list of mappers to write to Secrets instead of ConfigMaps").Default("").Envar("SECRET_MAPPERS").String()
+	shardMappers          = kingpin.Flag("shard-mappers", "Comma separated list of mappers to shard across multiple ConfigMaps").Default("").Envar("SHARD_MAPPERS").String()
+	shardMaxSize          = kingpin.Flag("shard-max-size", "Maximum size in bytes of the data in each shard").Default("921600").Envar("SHARD_MAX_SIZE").Int()
+	mapperUserAttrMaps    = kingpin.Flag("mapper-user-attr-map", "Mapper specific user attribute map overrides as <mapper>=<attr map>, may be repeated").Envar("MAPPER_USER_ATTR_MAPS").Strings()
+	mapperGroupAttrMaps   = kingpin.Flag("mapper-group-attr-map", "Mapper specific group attribute map overrides as <mapper>=<attr map>, may be repeated").Envar("MAPPER_GROUP_ATTR_MAPS").Strings()
+	mappersUserPrefix     = kingpin.Flag("mappers-user-prefix", "Comma separated mappers user prefix map").Default("").Envar("MAPPERS_USER_PREFIX").String()
 	mappersUserFilter     = kingpin.Flag("mappers-user-filter", "Comma separated mappers filters map for users").Default("").Envar("MAPPERS_USER_FILTER").String()
-	namespace             = kingpin.Flag("namespace", "namespace for ConfigMaps").Envar("NAMESPACE").Required().String()
+	namespace             = kingpin.Flag("namespace", "Comma separated list of namespaces for ConfigMaps").Envar("NAMESPACE").String()
+	namespaceSelector     = kingpin.Flag("namespace-selector", "Label selector of namespaces for ConfigMaps, evaluated every run").Default("").Envar("NAMESPACE_SELECTOR").String()
 	userPrefix            = kingpin.Flag("user-prefix", "Prefix to add to user names").Envar("USER_PREFIX").String()
+	sshKeysFormat         = kingpin.Flag("ssh-keys-format", "Format of user-ssh-keys values, either json or authorized_keys").Default("json").Envar("SSH_KEYS_FORMAT").String()
+	configMapNames        = kingpin.Flag("configmap-names", "Comma separated map of mapper to ConfigMap name").Default("").Envar("CONFIGMAP_NAMES").String()
+	configMapPrefix       = kingpin.Flag("configmap-prefix", "Prefix to add to all ConfigMap names").Default("").Envar("CONFIGMAP_PREFIX").String()
+	configMapSuffix       = kingpin.Flag("configmap-suffix", "Suffix to add to all ConfigMap names").Default("").Envar("CONFIGMAP_SUFFIX").String()
+	configMapLabels       = kingpin.Flag("configmap-labels", "Comma separated key=value labels to add to ConfigMaps").Default("").Envar("CONFIGMAP_LABELS").String()
+	configMapAnnotations  = kingpin.Flag("configmap-annotations", "Comma separated key=value annotations to add to ConfigMaps").Default("").Envar("CONFIGMAP_ANNOTATIONS").String()
+	ownerReference        = kingpin.Flag("owner-reference", "Owner of ConfigMaps in the same namespace as <kind>/<name>, kind is one of Deployment, StatefulSet or DaemonSet").Default("").Envar("OWNER_REFERENCE").String()
+	instance              = kingpin.Flag("instance", "Name of this instance, used to label ConfigMaps so they can be pruned").Default("k8-ldap-configmap").Envar("INSTANCE").String()
+	pruneMode             = kingpin.Flag("prune", "How to prune ConfigMaps of this instance whose mapper is not enabled, One of: [none, delete, dry-run]").Default("none").Envar("PRUNE").Enum(validPruneModes...)
+	customResources       = kingpin.Flag("custom-resources", "Maintain cluster scoped LDAPUser and LDAPGroup custom resources for every user and group").Default("false").Envar("CUSTOM_RESOURCES").Bool()
+	openshiftGroups       = kingpin.Flag("openshift-groups", "Maintain OpenShift user.openshift.io/v1 groups for every LDAP group").Default("false").Envar("OPENSHIFT_GROUPS").Bool()
+	kyvernoGlobalContext  = kingpin.Flag("kyverno-global-context", "Maintain a Kyverno GlobalContextEntry for the ConfigMap of every mapper").Default("false").Envar("KYVERNO_GLOBAL_CONTEXT").Bool()
+	kyvernoNamespace      = kingpin.Flag("kyverno-namespace", "Namespace of the ConfigMaps referenced by Kyverno GlobalContextEntries, defaults to the namespace when only one is given").Envar("KYVERNO_NAMESPACE").String()
+	kyvernoRefresh        = kingpin.Flag("kyverno-refresh-interval", "Interval Kyverno refreshes GlobalContextEntries from ConfigMaps").Default("5m").Envar("KYVERNO_REFRESH_INTERVAL").Duration()
+	sinkType              = kingpin.Flag("sink", "Where to write mapper data, One of: [kubernetes, filesystem, git]").Default("kubernetes").Envar("SINK").Enum(validSinks...)
+	sinkDir               = kingpin.Flag("sink-dir", "Directory to write mapper data to with the filesystem sink or path of the repository with the git sink").Envar("SINK_DIR").String()
+	sinkFormat            = kingpin.Flag("sink-format", "Format of the filesystem sink, One of: [json, configmap]").Default("json").Envar("SINK_FORMAT").Enum(validSinkFormats...)
+	gitPath               = kingpin.Flag("git-path", "Directory within the repository to write ConfigMaps to with the git sink").Envar("GIT_PATH").String()
+	gitRemote             = kingpin.Flag("git-remote", "URL of the remote to fetch from and push to with the git sink").Envar("GIT_REMOTE").String()
+	gitBranch             = kingpin.Flag("git-branch", "Branch to commit to with the git sink").Default("main").Envar("GIT_BRANCH").String()
+	gitUsername           = kingpin.Flag("git-username", "Username for HTTP authentication to the git remote").Envar("GIT_USERNAME").String()
+	gitPassword           = kingpin.Flag("git-password", "Password or token for HTTP authentication to the git remote").Envar("GIT_PASSWORD").String()
+	gitAuthorName         = kingpin.Flag("git-author-name", "Name of the author of git commits").Default(appName).Envar("GIT_AUTHOR_NAME").String()
+	gitAuthorEmail        = kingpin.Flag("git-author-email", "Email of the author of git commits").Default(appName + "@localhost").Envar("GIT_AUTHOR_EMAIL").String()
+	forceConflicts        = kingpin.Flag("force-conflicts", "Take ownership of ConfigMap fields owned by other field managers when applying").Default("false").Envar("FORCE_CONFLICTS").Bool()
 	interval              = kingpin.Flag("interval", "Duration between sync runs").Default("5m").Envar("INTERLVAL").Duration()
 	listenAddress         = kingpin.Flag("listen-address", "Address to listen for HTTP requests").Default(":8080").Envar("LISTEN_ADDRESS").String()
 	processMetrics        = kingpin.Flag("process-metrics", "Collect metrics about running process such as CPU and memory and Go stats").Default("true").Envar("PROCESS_METRICS").Bool()
 	kubeconfig            = kingpin.Flag("kubeconfig", "Path to kubeconfig when running outside Kubernetes cluster").Default("").Envar("KUBECONFIG").String()
 	logLevel              = kingpin.Flag("log-level", "Log level, One of: [debug, info, warn, error]").Default("info").Envar("LOG_LEVEL").Enum(promslog.LevelFlagOptions...)
 	logFormat             = kingpin.Flag("log-format", "Log format, One of: [logfmt, json]").Default("logfmt").Envar("LOG_FORMAT").Enum(promslog.FormatFlagOptions...)
+	ldapUserGVR           = schema.GroupVersionResource{Group: "ldap.osc.edu", Version: "v1alpha1", Resource: "ldapusers"}
+	ldapGroupGVR          = schema.GroupVersionResource{Group: "ldap.osc.edu", Version: "v1alpha1", Resource: "ldapgroups"}
+	globalContextEntryGVR = schema.GroupVersionResource{Group: "kyverno.io", Version: "v2alpha1", Resource: "globalcontextentries"}
+	openshiftGroupGVR     = schema.GroupVersionResource{Group: "user.openshift.io", Version: "v1", Resource: "groups"}
+	validPruneModes       = []string{"none", "delete", "dry-run"}
+	validSinks            = []string{"kubernetes", "filesystem", "git"}
+	validSinkFormats      = []string{sink.FormatJSON, sink.FormatConfigMap}
+	validOwnerKinds       = []string{"Deployment", "StatefulSet", "DaemonSet"}
 	validLdapMemberScheme = []string{"memberof", "member", "memberuid"}
 )
 
@@ -96,24 +175,34 @@ func main() {
 	if err != nil {
 		os.Exit(1)
 	}
+	registerMappers()
 
-	var config *rest.Config
-	if *kubeconfig == "" {
-		logger.Info("Loading in cluster kubeconfig", "kubeconfig", *kubeconfig)
-		config, err = rest.InClusterConfig()
-	} else {
-		logger.Info("Loading kubeconfig", "kubeconfig", *kubeconfig)
-		config, err = clientcmd.BuildConfigFromFlags("", *kubeconfig)
-	}
-	if err != nil {
-		logger.Error("Error loading kubeconfig", "err", err)
-		os.Exit(1)
-	}
+	var clientset kubernetes.Interface
+	var dynamicClient dynamic.Interface
+	if *sinkType == "kubernetes" {
+		var config *rest.Config
+		if *kubeconfig == "" {
+			logger.Info("Loading in cluster kubeconfig", "kubeconfig", *kubeconfig)
+			config, err = rest.InClusterConfig()
+		} else {
+			logger.Info("Loading kubeconfig", "kubeconfig", *kubeconfig)
+			config, err = clientcmd.BuildConfigFromFlags("", *kubeconfig)
+		}
+		if err != nil {
+			logger.Error("Error loading kubeconfig", "err", err)
+			os.Exit(1)
+		}
 
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		logger.Error("Unable to generate Clientset", "err", err)
-		os.Exit(1)
+		clientset, err = kubernetes.NewForConfig(config)
+		if err != nil {
+			logger.Error("Unable to generate Clientset", "err", err)
+			os.Exit(1)
+		}
+		dynamicClient, err = dynamic.NewForConfig(config)
+		if err != nil {
+			logger.Error("Unable to generate dynamic client", "err", err)
+			os.Exit(1)
+		}
 	}
 
 	c := createConfig()
@@ -144,7 +233,7 @@ func main() {
 		var errNum float64
 		start := time.Now()
 		metrics.MetricLastRun.Set(float64(start.Unix()))
-		err = run(mappers, c, clientset, logger)
+		err = run(mappers, c, clientset, dynamicClient, logger)
 		metrics.MetricDuration.Set(time.Since(start).Seconds())
 		if err != nil {
 			errNum = 1
@@ -155,31 +244,45 @@ func main() {
 	}
 }
 
-func run(mappers []mapper.Mapper, config *config.Config, clientset kubernetes.Interface, logger *slog.Logger) error {
+func run(mappers []mapper.Mapper, config *config.Config, clientset kubernetes.Interface, dynamicClient dynamic.Interface, logger *slog.Logger) error {
 	l, err := localldap.LDAPConnect(config, logger)
 	if err != nil {
 		return err
 	}
 	defer l.Close()
 
-	var groupResults, userResults *ldap.SearchResult
-	var groupErr, userErr error
+	var groupResults, userResults, netgroupResults, sudoResults *ldap.SearchResult
+	var groupErr, userErr, netgroupErr, sudoErr error
 	searchWG := &sync.WaitGroup{}
-	searchWG.Add(2)
+	searchWG.Add(4)
 	go func() {
 		defer searchWG.Done()
-		if len(config.RequiredGroupAttrs) == 0 {
+		if len(config.RequiredGroupAttrs) == 0 && len(config.GroupLDAPAttrs) == 0 {
 			return
 		}
 		groupResults, groupErr = localldap.LDAPGroups(l, config.GroupFilter, config, logger)
 	}()
 	go func() {
 		defer searchWG.Done()
-		if len(config.RequiredUserAttrs) == 0 {
+		if len(config.RequiredUserAttrs) == 0 && len(config.UserLDAPAttrs) == 0 {
 			return
 		}
 		userResults, userErr = localldap.LDAPUsers(l, config.UserFilter, config, logger)
 	}()
+	go func() {
+		defer searchWG.Done()
+		if !mapper.NetgroupsRequired(config.EnabledMappers) {
+			return
+		}
+		netgroupResults, netgroupErr = localldap.LDAPNetgroups(l, config.NetgroupFilter, config, logger)
+	}()
+	go func() {
+		defer searchWG.Done()
+		if !mapper.SudoRolesRequired(config.EnabledMappers) {
+			return
+		}
+		sudoResults, sudoErr = localldap.LDAPSudoRoles(l, config.SudoersFilter, config, logger)
+	}()
 	searchWG.Wait()
 	if groupErr != nil {
 		return groupErr
@@ -187,15 +290,63 @@ func run(mappers []mapper.Mapper, config *config.Config, clientset kubernetes.In
 	if userErr != nil {
 		return userErr
 	}
+	if netgroupErr != nil {
+		return netgroupErr
+	}
+	if sudoErr != nil {
+		return sudoErr
+	}
 
+	var namespaces []string
+	owners := make(map[string]*metav1.OwnerReference)
+	ownerErrs := make(map[string]error)
+	if config.Sink == "kubernetes" {
+		namespaces, err = getNamespaces(clientset, config, logger)
+		if err != nil {
+			return err
+		}
+		for _, ns := range namespaces {
+			owners[ns], ownerErrs[ns] = getOwnerReference(clientset, config, ns, logger)
+		}
+	}
+	now := time.Now().UTC()
+	syncTime := now.Format(time.RFC3339)
+
+	var gitConfigMaps []sink.ConfigMap
+	gitMutex := &sync.Mutex{}
 	errs, _ := errgroup.WithContext(context.Background())
+	if config.CustomResources {
+		errs.Go(func() error {
+			return syncCustomResources(dynamicClient, config, userResults, groupResults, logger)
+		})
+	}
+	if config.OpenShiftGroups {
+		errs.Go(func() error {
+			return syncOpenShiftGroups(dynamicClient, config, userResults, groupResults, logger)
+		})
+	}
 	for _, m := range mappers {
 		_m := m
 		errs.Go(func() error {
 			var err error
 			var mapperGroupResults, mapperUserResults *ldap.SearchResult
-			if filter, ok := config.MappersGroupFilter[_m.Name()]; ok {
-				mapperGroupResults, err = localldap.LDAPGroups(l, filter, config, logger)
+			if mapper.UsesNetgroups(mapper.MapperType(config.EnabledMappers, _m.Name())) {
+				if filter, ok := config.MappersGroupFilter[_m.Name()]; ok {
+					mapperGroupResults, err = localldap.LDAPNetgroups(l, filter, config, logger)
+					if err != nil {
+						return err
+					}
+				} else {
+					mapperGroupResults = netgroupResults
+				}
+			} else if filter, ok := config.MappersGroupFilter[_m.Name()]; ok {
+				mapperGroupResults, err = localldap.LDAPGroupsWithUserFilter(l, filter, mapperUserFilter(config, _m.Name()), config, logger)
+				if err != nil {
+					return err
+				}
+			} else if _, ok := config.MappersUserFilter[_m.Name()]; ok && config.NestedGroups && config.NestedGroupsInChain {
+				// Members in chain are searched with the user filter so must be searched again with the mapper user filter
+				mapperGroupResults, err = localldap.LDAPGroupsWithUserFilter(l, config.GroupFilter, mapperUserFilter(config, _m.Name()), config, logger)
 				if err != nil {
 					return err
 				}
@@ -210,97 +361,1074 @@ func run(mappers []mapper.Mapper, config *config.Config, clientset kubernetes.In
 			} else {
 				mapperUserResults = userResults
 			}
-			data, err := _m.GetData(mapperUserResults, mapperGroupResults)
+			var data map[string]string
+			switch m := _m.(type) {
+			case mapper.SudoRolesMapper:
+				data, err = m.GetSudoData(mapperUserResults, mapperGroupResults, sudoResults)
+			case mapper.AutomountMapper:
+				var automountResults *ldap.SearchResult
+				automountResults, err = localldap.LDAPAutomounts(l, m.MapName(), config, logger)
+				if err != nil {
+					metrics.MetricErrorsTotal.WithLabelValues(_m.Name()).Inc()
+					return err
+				}
+				data, err = m.GetAutomountData(mapperUserResults, automountResults)
+			default:
+				data, err = _m.GetData(mapperUserResults, mapperGroupResults)
+			}
 			if err != nil {
 				metrics.MetricErrorsTotal.WithLabelValues(_m.Name()).Inc()
 				return err
 			}
-			err = configmap(clientset, _m.ConfigMapName(), data, logger)
-			if err != nil {
+			if config.Sink == "filesystem" {
+				err = writeFilesystem(config, _m, data, logger)
+				if err != nil {
+					metrics.MetricErrorsTotal.WithLabelValues(_m.Name()).Inc()
+					return err
+				}
+				metrics.MetricLastSync.WithLabelValues(_m.Name()).Set(float64(now.Unix()))
+				return nil
+			}
+			if config.Sink == "git" {
+				meta := newObjectMeta(config, _m.Name(), nil)
+				gitMutex.Lock()
+				defer gitMutex.Unlock()
+				gitConfigMaps = append(gitConfigMaps, sink.ConfigMap{
+					Name:        _m.ConfigMapName(),
+					Labels:      meta.labels,
+					Annotations: meta.annotations,
+					Data:        data,
+				})
+				return nil
+			}
+			syncErrs := []error{}
+			for _, ns := range namespaces {
+				if ownerErrs[ns] != nil {
+					metrics.MetricNamespaceErrorsTotal.WithLabelValues(ns).Inc()
+					syncErrs = append(syncErrs, ownerErrs[ns])
+					continue
+				}
+				meta := newObjectMeta(config, _m.Name(), owners[ns])
+				meta.syncAnnotations = map[string]string{
+					lastSyncAnnotation:     syncTime,
+					userEntriesAnnotation:  strconv.Itoa(entriesCount(mapperUserResults)),
+					groupEntriesAnnotation: strconv.Itoa(entriesCount(mapperGroupResults)),
+				}
+				err = writeData(clientset, config, ns, _m, data, meta, logger)
+				if err != nil {
+					metrics.MetricNamespaceErrorsTotal.WithLabelValues(ns).Inc()
+					syncErrs = append(syncErrs, err)
+				}
+			}
+			if len(syncErrs) > 0 {
 				metrics.MetricErrorsTotal.WithLabelValues(_m.Name()).Inc()
-				return err
+				return errors.Join(syncErrs...)
 			}
+			metrics.MetricLastSync.WithLabelValues(_m.Name()).Set(float64(now.Unix()))
 			return nil
 		})
 	}
-	return errs.Wait()
+	if err := errs.Wait(); err != nil {
+		return err
+	}
+	if config.Sink == "git" {
+		if err := writeGit(config, gitConfigMaps, logger); err != nil {
+			return err
+		}
+		for _, m := range mappers {
+			metrics.MetricLastSync.WithLabelValues(m.Name()).Set(float64(now.Unix()))
+		}
+		return nil
+	}
+	if config.KyvernoGlobalContext {
+		// Namespaces from a selector are only known at run time so the Kyverno namespace is checked here
+		if !utils.SliceContains(namespaces, config.KyvernoNamespace) {
+			err := fmt.Errorf("kyverno namespace %s is not one of the namespaces written to", config.KyvernoNamespace)
+			logger.Error("Unable to sync GlobalContextEntries", "err", err)
+			metrics.MetricCustomResourceErrorsTotal.Inc()
+			return err
+		}
+		if err := globalContextEntries(dynamicClient, config, mappers, logger); err != nil {
+			return err
+		}
+	}
+	if config.Prune == "none" || config.Sink != "kubernetes" {
+		return nil
+	}
+	enabledMappers := []string{}
+	for _, m := range mappers {
+		enabledMappers = append(enabledMappers, m.Name())
+	}
+	pruneErrs := []error{}
+	for _, ns := range namespaces {
+		if err := prune(clientset, config, ns, enabledMappers, logger); err != nil {
+			metrics.MetricNamespaceErrorsTotal.WithLabelValues(ns).Inc()
+			pruneErrs = append(pruneErrs, err)
+		}
+	}
+	return errors.Join(pruneErrs...)
 }
 
-func configmap(clientset kubernetes.Interface, name string, data map[string]string, logger *slog.Logger) error {
-	var err error
-	configMap := corev1.ConfigMap{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "ConfigMap",
-			APIVersion: "v1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      name,
-			Namespace: *namespace,
-		},
-		Data: data,
-	}
-	var action string
-	if _, err = clientset.CoreV1().ConfigMaps(*namespace).Get(context.TODO(), name, metav1.GetOptions{}); k8errors.IsNotFound(err) {
-		action = "create"
-		_, err = clientset.CoreV1().ConfigMaps(*namespace).Create(context.TODO(), &configMap, metav1.CreateOptions{})
-	} else {
-		action = "update"
-		_, err = clientset.CoreV1().ConfigMaps(*namespace).Update(context.TODO(), &configMap, metav1.UpdateOptions{})
+// syncCustomResources creates, updates and deletes LDAPUser and LDAPGroup custom resources to match the users and groups
+func syncCustomResources(dynamicClient dynamic.Interface, config *config.Config, users *ldap.SearchResult, groups *ldap.SearchResult, logger *slog.Logger) error {
+	userSpecs, groupSpecs, err := mapper.GetLDAPResources(users, groups, config, logger)
+	if err != nil {
+		metrics.MetricCustomResourceErrorsTotal.Inc()
+		return err
 	}
+	userObjects := make(map[string]map[string]interface{})
+	for name, spec := range userSpecs {
+		specMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&spec)
+		if err != nil {
+			metrics.MetricCustomResourceErrorsTotal.Inc()
+			return err
+		}
+		userObjects[name] = map[string]interface{}{"spec": specMap}
+	}
+	groupObjects := make(map[string]map[string]interface{})
+	for name, spec := range groupSpecs {
+		specMap, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&spec)
+		if err != nil {
+			metrics.MetricCustomResourceErrorsTotal.Inc()
+			return err
+		}
+		groupObjects[name] = map[string]interface{}{"spec": specMap}
+	}
+	err = errors.Join(
+		syncCustomResource(dynamicClient, config, ldapUserGVR, "LDAPUser", userObjects, validation.IsDNS1123Subdomain, logger),
+		syncCustomResource(dynamicClient, config, ldapGroupGVR, "LDAPGroup", groupObjects, validation.IsDNS1123Subdomain, logger),
+	)
+	if err != nil {
+		metrics.MetricCustomResourceErrorsTotal.Inc()
+	}
+	return err
+}
+
+// syncOpenShiftGroups creates, updates and deletes OpenShift groups to match the LDAP groups
+func syncOpenShiftGroups(dynamicClient dynamic.Interface, config *config.Config, users *ldap.SearchResult, groups *ldap.SearchResult, logger *slog.Logger) error {
+	openshiftGroups, err := mapper.GetOpenShiftGroups(users, groups, config, logger)
+	if err != nil {
+		metrics.MetricCustomResourceErrorsTotal.Inc()
+		return err
+	}
+	groupObjects := make(map[string]map[string]interface{})
+	for name, members := range openshiftGroups {
+		users := make([]interface{}, len(members))
+		for i, member := range members {
+			users[i] = member
+		}
+		groupObjects[name] = map[string]interface{}{"users": users}
+	}
+	// OpenShift group names are only required to be valid path segments so names such as Domain_Admins are allowed
+	err = syncCustomResource(dynamicClient, config, openshiftGroupGVR, "Group", groupObjects, path.IsValidPathSegmentName, logger)
+	if err != nil {
+		metrics.MetricCustomResourceErrorsTotal.Inc()
+	}
+	return err
+}
+
+// syncCustomResource makes the resources of a kind managed by this instance match the objects keyed by name,
+// each object holds the top level fields of the resource such as spec and names are checked with the validator of the kind
+func syncCustomResource(dynamicClient dynamic.Interface, config *config.Config, gvr schema.GroupVersionResource, kind string, objects map[string]map[string]interface{}, validateName func(name string) []string, logger *slog.Logger) error {
+	resource := dynamicClient.Resource(gvr)
+	selector := labels.Set{managedByLabel: appName, instanceLabel: config.Instance}.String()
+	list, err := resource.List(context.TODO(), metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		logger.Error("Error listing custom resources", "kind", kind, "err", err)
+		return err
+	}
+	existing := make(map[string]unstructured.Unstructured)
+	for _, item := range list.Items {
+		existing[item.GetName()] = item
+	}
+	syncErrs := []error{}
+	for name, content := range objects {
+		if msgs := validateName(name); len(msgs) > 0 {
+			logger.Warn("Skipping custom resource with invalid name", "kind", kind, "name", name, "err", strings.Join(msgs, ", "))
+			metrics.MetricCustomResourceWritesTotal.WithLabelValues(kind, "skipped").Inc()
+			continue
+		}
+		contentJSON, err := json.Marshal(content)
+		if err != nil {
+			syncErrs = append(syncErrs, err)
+			continue
+		}
+		sum := sha256.Sum256(contentJSON)
+		hash := hex.EncodeToString(sum[:])
+		obj := &unstructured.Unstructured{Object: make(map[string]interface{})}
+		for field, value := range content {
+			obj.Object[field] = value
+		}
+		obj.SetAPIVersion(gvr.GroupVersion().String())
+		obj.SetKind(kind)
+		obj.SetName(name)
+		obj.SetLabels(map[string]string{
+			managedByLabel: appName,
+			instanceLabel:  config.Instance,
+		})
+		obj.SetAnnotations(map[string]string{dataHashAnnotation: hash})
+		action := "create"
+		if live, ok := existing[name]; !ok {
+			_, err = resource.Create(context.TODO(), obj, metav1.CreateOptions{FieldManager: fieldManager})
+			if k8errors.IsAlreadyExists(err) {
+				logger.Warn("Skipping resource not managed by this instance", "kind", kind, "name", name)
+				metrics.MetricCustomResourceWritesTotal.WithLabelValues(kind, "skipped").Inc()
+				continue
+			}
+		} else if live.GetAnnotations()[dataHashAnnotation] == hash {
+			action = "unchanged"
+		} else {
+			action = "update"
+			obj.SetResourceVersion(live.GetResourceVersion())
+			_, err = resource.Update(context.TODO(), obj, metav1.UpdateOptions{FieldManager: fieldManager})
+		}
+		if err != nil {
+			logger.Error("Failed to sync custom resource", "kind", kind, "action", action, "name", name, "err", err)
+			syncErrs = append(syncErrs, err)
+			continue
+		}
+		logger.Debug("Custom resource sync successful", "kind", kind, "action", action, "name", name)
+		metrics.MetricCustomResourceWritesTotal.WithLabelValues(kind, action).Inc()
+	}
+	for name := range existing {
+		if _, ok := objects[name]; ok {
+			continue
+		}
+		err = resource.Delete(context.TODO(), name, metav1.DeleteOptions{})
+		if err != nil && !k8errors.IsNotFound(err) {
+			logger.Error("Failed to delete custom resource", "kind", kind, "name", name, "err", err)
+			syncErrs = append(syncErrs, err)
+			continue
+		}
+		logger.Info("Deleted custom resource", "kind", kind, "name", name)
+		metrics.MetricCustomResourceWritesTotal.WithLabelValues(kind, "delete").Inc()
+	}
+	logger.Info("Custom resources sync complete", "kind", kind, "count", len(objects))
+	return errors.Join(syncErrs...)
+}
+
+// prune deletes the ConfigMaps and Secrets managed by this instance whose mapper is no longer enabled
+func prune(clientset kubernetes.Interface, config *config.Config, namespace string, enabledMappers []string, logger *slog.Logger) error {
+	selector := labels.Set{managedByLabel: appName, instanceLabel: config.Instance}.String()
+	listOptions := metav1.ListOptions{LabelSelector: selector}
+	configMaps, err := clientset.CoreV1().ConfigMaps(namespace).List(context.TODO(), listOptions)
+	if err != nil {
+		logger.Error("Error listing managed ConfigMaps", "namespace", namespace, "err", err)
+		return err
+	}
+	objects := []metav1.Object{}
+	for i := range configMaps.Items {
+		objects = append(objects, &configMaps.Items[i])
+	}
+	// Secrets are always listed so the Secret of the last secret mapper is pruned once it is disabled
+	secrets, err := clientset.CoreV1().Secrets(namespace).List(context.TODO(), listOptions)
+	if err != nil {
+		logger.Error("Error listing managed Secrets", "namespace", namespace, "err", err)
+		return err
+	}
+	for i := range secrets.Items {
+		objects = append(objects, &secrets.Items[i])
+	}
+	for _, obj := range objects {
+		mapperName := obj.GetLabels()[mapperLabel]
+		if utils.SliceContains(enabledMappers, mapperName) {
+			continue
+		}
+		kind := "ConfigMap"
+		if _, ok := obj.(*corev1.Secret); ok {
+			kind = "Secret"
+		}
+		if config.Prune == "dry-run" {
+			logger.Info(fmt.Sprintf("Would prune %s of disabled mapper", kind), "name", obj.GetName(), "namespace", namespace, "mapper", mapperName)
+			metrics.MetricPrunedTotal.WithLabelValues(obj.GetName(), config.Prune).Inc()
+			continue
+		}
+		if kind == "Secret" {
+			err = clientset.CoreV1().Secrets(namespace).Delete(context.TODO(), obj.GetName(), metav1.DeleteOptions{})
+		} else {
+			err = clientset.CoreV1().ConfigMaps(namespace).Delete(context.TODO(), obj.GetName(), metav1.DeleteOptions{})
+		}
+		if err != nil && !k8errors.IsNotFound(err) {
+			logger.Error(fmt.Sprintf("Failed to prune %s", kind), "name", obj.GetName(), "namespace", namespace, "mapper", mapperName, "err", err)
+			return err
+		}
+		logger.Info(fmt.Sprintf("Pruned %s of disabled mapper", kind), "name", obj.GetName(), "namespace", namespace, "mapper", mapperName)
+		metrics.MetricPrunedTotal.WithLabelValues(obj.GetName(), config.Prune).Inc()
+		metrics.MetricConfigMapKeys.DeleteLabelValues(obj.GetName(), namespace)
+		metrics.MetricConfigMapSize.DeleteLabelValues(obj.GetName(), namespace)
+	}
+	return nil
+}
+
+// writeData writes the data of a mapper to a ConfigMap or Secret,
+// sharding the data when the mapper is configured for sharding
+func writeData(clientset kubernetes.Interface, config *config.Config, namespace string, m mapper.Mapper, data map[string]string, meta objectMeta, logger *slog.Logger) error {
+	isSecret := utils.SliceContains(config.SecretMappers, m.Name())
+	write := configmap
+	if isSecret {
+		write = secret
+	}
+	name := m.ConfigMapName()
+	indexName := fmt.Sprintf("%s-index", name)
+	indexFound := false
+	previousShards := 0
+	index, err := clientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), indexName, metav1.GetOptions{})
 	if err == nil {
-		logger.Info("ConfigMap sync successful", "action", action, "name", name, "namespace", *namespace)
-		metrics.MetricConfigMapKeys.WithLabelValues(name).Set(float64(len(data)))
-		configMapJSON, err := json.Marshal(configMap)
+		indexFound = true
+		previousShards, _ = strconv.Atoi(index.Data["shards"])
+	} else if !k8errors.IsNotFound(err) {
+		logger.Error("Error getting shard index", "name", indexName, "namespace", namespace, "err", err)
+		return err
+	}
+	if !utils.SliceContains(config.ShardMappers, m.Name()) {
+		if err := write(clientset, namespace, name, data, meta, logger); err != nil {
+			return err
+		}
+		if !indexFound {
+			return nil
+		}
+		// The mapper was previously sharded so remove the shards and their index
+		if err := deleteShards(clientset, namespace, name, 0, previousShards, isSecret, logger); err != nil {
+			return err
+		}
+		err = clientset.CoreV1().ConfigMaps(namespace).Delete(context.TODO(), indexName, metav1.DeleteOptions{})
+		if err != nil && !k8errors.IsNotFound(err) {
+			logger.Error("Failed to delete stale shard index", "name", indexName, "namespace", namespace, "err", err)
+			return err
+		}
+		logger.Info("Deleted stale shard index", "name", indexName, "namespace", namespace)
+		metrics.MetricConfigMapKeys.DeleteLabelValues(indexName, namespace)
+		metrics.MetricConfigMapSize.DeleteLabelValues(indexName, namespace)
+		metrics.MetricShards.DeleteLabelValues(name)
+		return nil
+	}
+	shards := shardData(data, config.ShardMaxSize)
+	for i, shard := range shards {
+		if err := write(clientset, namespace, fmt.Sprintf("%s-%d", name, i), shard, meta, logger); err != nil {
+			return err
+		}
+	}
+	indexData := map[string]string{
+		"shards": strconv.Itoa(len(shards)),
+		"hash":   "fnv32a",
+	}
+	if err := configmap(clientset, namespace, indexName, indexData, meta, logger); err != nil {
+		return err
+	}
+	if !indexFound {
+		// The mapper was previously unsharded so remove the unsharded object now the index points to the shards
+		if isSecret {
+			err = clientset.CoreV1().Secrets(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+		} else {
+			err = clientset.CoreV1().ConfigMaps(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+		}
+		if err != nil && !k8errors.IsNotFound(err) {
+			logger.Error("Failed to delete unsharded object", "name", name, "namespace", namespace, "err", err)
+			return err
+		}
+		if err == nil {
+			logger.Info("Deleted unsharded object", "name", name, "namespace", namespace)
+			metrics.MetricConfigMapKeys.DeleteLabelValues(name, namespace)
+			metrics.MetricConfigMapSize.DeleteLabelValues(name, namespace)
+		}
+	}
+	if err := deleteShards(clientset, namespace, name, len(shards), previousShards, isSecret, logger); err != nil {
+		return err
+	}
+	metrics.MetricShards.WithLabelValues(name).Set(float64(len(shards)))
+	return nil
+}
+
+// deleteShards deletes the shards of a ConfigMap or Secret from the start shard up to but not including the end shard
+func deleteShards(clientset kubernetes.Interface, namespace string, name string, start int, end int, isSecret bool, logger *slog.Logger) error {
+	for i := start; i < end; i++ {
+		shardName := fmt.Sprintf("%s-%d", name, i)
+		var err error
+		if isSecret {
+			err = clientset.CoreV1().Secrets(namespace).Delete(context.TODO(), shardName, metav1.DeleteOptions{})
+		} else {
+			err = clientset.CoreV1().ConfigMaps(namespace).Delete(context.TODO(), shardName, metav1.DeleteOptions{})
+		}
+		if err != nil && !k8errors.IsNotFound(err) {
+			logger.Error("Failed to delete stale shard", "name", shardName, "namespace", namespace, "err", err)
+			return err
+		}
+		logger.Info("Deleted stale shard", "name", shardName, "namespace", namespace)
+		metrics.MetricConfigMapKeys.DeleteLabelValues(shardName, namespace)
+		metrics.MetricConfigMapSize.DeleteLabelValues(shardName, namespace)
+	}
+	return nil
+}
+
+// writeFilesystem writes the data of a mapper to the sink directory
+func writeFilesystem(config *config.Config, m mapper.Mapper, data map[string]string, logger *slog.Logger) error {
+	name := m.ConfigMapName()
+	isSecret := utils.SliceContains(config.SecretMappers, m.Name())
+	action, err := sink.WriteFilesystem(config.SinkDir, config.SinkFormat, name, data, isSecret, logger)
+	if err != nil {
+		logger.Error("Error writing to filesystem", "name", name, "dir", config.SinkDir, "err", err)
+		return err
+	}
+	logger.Info("Filesystem sync successful", "action", action, "name", name, "dir", config.SinkDir)
+	metrics.MetricWritesTotal.WithLabelValues(name, action).Inc()
+	metrics.MetricConfigMapKeys.WithLabelValues(name, "").Set(float64(len(data)))
+	return nil
+}
+
+// writeGit commits the ConfigMaps of all mappers to the Git repository
+func writeGit(config *config.Config, configMaps []sink.ConfigMap, logger *slog.Logger) error {
+	opts := sink.GitOptions{
+		Dir:         config.SinkDir,
+		Path:        config.GitPath,
+		Remote:      config.GitRemote,
+		Branch:      config.GitBranch,
+		Username:    config.GitUsername,
+		Password:    config.GitPassword,
+		AuthorName:  config.GitAuthorName,
+		AuthorEmail: config.GitAuthorEmail,
+	}
+	actions, err := sink.WriteGit(opts, configMaps, logger)
+	if err != nil {
+		metrics.MetricGitErrorsTotal.Inc()
+		return err
+	}
+	for _, cm := range configMaps {
+		logger.Info("Git sync successful", "action", actions[cm.Name], "name", cm.Name, "dir", config.SinkDir)
+		metrics.MetricWritesTotal.WithLabelValues(cm.Name, actions[cm.Name]).Inc()
+		metrics.MetricConfigMapKeys.WithLabelValues(cm.Name, "").Set(float64(len(cm.Data)))
+	}
+	return nil
+}
+
+// shardKey returns the shard of a key using a stable hash
+func shardKey(key string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// shardData splits data into the fewest shards where the keys and values
+// of each shard do not exceed maxSize bytes, a single key larger than maxSize
+// can not be split so the shard holding it will exceed maxSize
+func shardData(data map[string]string, maxSize int) []map[string]string {
+	total := 0
+	for key, value := range data {
+		total += len(key) + len(value)
+	}
+	count := 1
+	if maxSize > 0 {
+		count = max(1, (total+maxSize-1)/maxSize)
+	}
+	for {
+		shards := make([]map[string]string, count)
+		sizes := make([]int, count)
+		for i := range shards {
+			shards[i] = make(map[string]string)
+		}
+		for key, value := range data {
+			shard := shardKey(key, count)
+			shards[shard][key] = value
+			sizes[shard] += len(key) + len(value)
+		}
+		fits := true
+		for _, size := range sizes {
+			if maxSize > 0 && size > maxSize {
+				fits = false
+			}
+		}
+		if fits || count >= len(data) {
+			return shards
+		}
+		count++
+	}
+}
+
+// getNamespaces returns the namespaces to write ConfigMaps, the namespace selector
+// is evaluated each time so new namespaces are found without a restart
+func getNamespaces(clientset kubernetes.Interface, config *config.Config, logger *slog.Logger) ([]string, error) {
+	namespaces := append([]string{}, config.Namespaces...)
+	if config.NamespaceSelector != "" {
+		namespaceList, err := clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{LabelSelector: config.NamespaceSelector})
+		if err != nil {
+			logger.Error("Unable to list namespaces", "selector", config.NamespaceSelector, "err", err)
+			return nil, err
+		}
+		for _, ns := range namespaceList.Items {
+			if ns.Status.Phase == corev1.NamespaceTerminating {
+				continue
+			}
+			if !utils.SliceContains(namespaces, ns.Name) {
+				namespaces = append(namespaces, ns.Name)
+			}
+		}
+	}
+	sort.Strings(namespaces)
+	logger.Debug("Namespaces for ConfigMaps", "namespaces", strings.Join(namespaces, ","))
+	return namespaces, nil
+}
+
+func configmap(clientset kubernetes.Interface, namespace string, name string, data map[string]string, meta objectMeta, logger *slog.Logger) error {
+	hash := meta.hash(data)
+	configMap := corev1ac.ConfigMap(name, namespace).
+		WithLabels(meta.labels).
+		WithAnnotations(meta.annotations).
+		WithAnnotations(meta.syncAnnotations).
+		WithAnnotations(map[string]string{dataHashAnnotation: hash}).
+		WithData(data)
+	if meta.owner != nil {
+		configMap.WithOwnerReferences(ownerReferenceApply(meta.owner))
+	}
+	action := "update"
+	live, err := clientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if k8errors.IsNotFound(err) {
+		action = "create"
+	} else if err != nil {
+		logger.Error("Error getting ConfigMap", "name", name, "namespace", namespace, "err", err)
+		return err
+	} else if unchanged(live, live.Data, hash, data, meta) {
+		action = "unchanged"
+	}
+	if action != "unchanged" {
+		_, err = clientset.CoreV1().ConfigMaps(namespace).Apply(context.TODO(), configMap, applyOptions(meta.forceConflicts))
 		if err != nil {
-			logger.Error("Unable to marshall configmap to JSON", "name", name, "namespace", *namespace, "err", err)
+			applyError("ConfigMap", namespace, name, err, logger)
 			return err
 		}
-		metrics.MetricConfigMapSize.WithLabelValues(name).Set(float64(len(configMapJSON)))
-	} else {
-		logger.Error("Failed to sync ConfigMap", "action", action, "name", name, "namespace", *namespace, "err", err)
+	}
+	logger.Info("ConfigMap sync successful", "action", action, "name", name, "namespace", namespace)
+	metrics.MetricWritesTotal.WithLabelValues(name, action).Inc()
+	metrics.MetricConfigMapKeys.WithLabelValues(name, namespace).Set(float64(len(data)))
+	configMapJSON, err := json.Marshal(configMap)
+	if err != nil {
+		logger.Error("Unable to marshall configmap to JSON", "name", name, "namespace", namespace, "err", err)
+		return err
+	}
+	metrics.MetricConfigMapSize.WithLabelValues(name, namespace).Set(float64(len(configMapJSON)))
+	return nil
+}
+
+// globalContextEntries creates, updates and deletes the Kyverno GlobalContextEntries that reference the ConfigMap of each mapper,
+// mappers written to Secrets or shards are skipped
+func globalContextEntries(dynamicClient dynamic.Interface, config *config.Config, mappers []mapper.Mapper, logger *slog.Logger) error {
+	entries := make(map[string]map[string]interface{})
+	for _, m := range mappers {
+		if utils.SliceContains(config.SecretMappers, m.Name()) || utils.SliceContains(config.ShardMappers, m.Name()) {
+			logger.Debug("Skipping GlobalContextEntry of secret or shard mapper", "mapper", m.Name())
+			continue
+		}
+		// Entries are named after the mapper so policies keep working when the ConfigMap name changes
+		entries[m.Name()] = map[string]interface{}{
+			"spec": map[string]interface{}{
+				"apiCall": map[string]interface{}{
+					"urlPath":         fmt.Sprintf("/api/v1/namespaces/%s/configmaps/%s", config.KyvernoNamespace, m.ConfigMapName()),
+					"refreshInterval": config.KyvernoRefreshInterval.String(),
+				},
+			},
+		}
+	}
+	err := syncCustomResource(dynamicClient, config, globalContextEntryGVR, "GlobalContextEntry", entries, validation.IsDNS1123Subdomain, logger)
+	if err != nil {
+		metrics.MetricCustomResourceErrorsTotal.Inc()
 	}
 	return err
 }
 
+func secret(clientset kubernetes.Interface, namespace string, name string, data map[string]string, meta objectMeta, logger *slog.Logger) error {
+	hash := meta.hash(data)
+	secretData := make(map[string][]byte)
+	for key, value := range data {
+		secretData[key] = []byte(value)
+	}
+	secret := corev1ac.Secret(name, namespace).
+		WithLabels(meta.labels).
+		WithAnnotations(meta.annotations).
+		WithAnnotations(meta.syncAnnotations).
+		WithAnnotations(map[string]string{dataHashAnnotation: hash}).
+		WithType(corev1.SecretTypeOpaque).
+		WithData(secretData)
+	if meta.owner != nil {
+		secret.WithOwnerReferences(ownerReferenceApply(meta.owner))
+	}
+	action := "update"
+	live, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if k8errors.IsNotFound(err) {
+		action = "create"
+	} else if err != nil {
+		logger.Error("Error getting Secret", "name", name, "namespace", namespace, "err", err)
+		return err
+	} else {
+		liveData := make(map[string]string)
+		for key, value := range live.Data {
+			liveData[key] = string(value)
+		}
+		if unchanged(live, liveData, hash, data, meta) {
+			action = "unchanged"
+		}
+	}
+	if action != "unchanged" {
+		_, err = clientset.CoreV1().Secrets(namespace).Apply(context.TODO(), secret, applyOptions(meta.forceConflicts))
+		if err != nil {
+			applyError("Secret", namespace, name, err, logger)
+			return err
+		}
+	}
+	logger.Info("Secret sync successful", "action", action, "name", name, "namespace", namespace)
+	metrics.MetricWritesTotal.WithLabelValues(name, action).Inc()
+	metrics.MetricConfigMapKeys.WithLabelValues(name, namespace).Set(float64(len(data)))
+	secretJSON, err := json.Marshal(secret)
+	if err != nil {
+		logger.Error("Unable to marshall secret to JSON", "name", name, "namespace", namespace, "err", err)
+		return err
+	}
+	metrics.MetricConfigMapSize.WithLabelValues(name, namespace).Set(float64(len(secretJSON)))
+	return nil
+}
+
+// objectMeta is the metadata set on the objects written for a mapper,
+// sync annotations change every run so are not included in the hash
+type objectMeta struct {
+	labels          map[string]string
+	annotations     map[string]string
+	syncAnnotations map[string]string
+	owner           *metav1.OwnerReference
+	forceConflicts  bool
+}
+
+func newObjectMeta(config *config.Config, mapperName string, owner *metav1.OwnerReference) objectMeta {
+	labels := make(map[string]string)
+	for key, value := range config.Labels {
+		labels[key] = value
+	}
+	labels[managedByLabel] = appName
+	labels[mapperLabel] = mapperName
+	labels[instanceLabel] = config.Instance
+	annotations := make(map[string]string)
+	for key, value := range config.Annotations {
+		annotations[key] = value
+	}
+	annotations[ldapURLAnnotation] = config.LdapURL
+	return objectMeta{
+		labels:         labels,
+		annotations:    annotations,
+		owner:          owner,
+		forceConflicts: config.ForceConflicts,
+	}
+}
+
+// hash returns the SHA-256 of the data and metadata, map keys are sorted when marshalled so the hash is stable
+func (o objectMeta) hash(data map[string]string) string {
+	hashJSON, _ := json.Marshal(struct {
+		Data        map[string]string
+		Labels      map[string]string
+		Annotations map[string]string
+		Owner       *metav1.OwnerReference
+	}{data, o.labels, o.annotations, o.owner})
+	sum := sha256.Sum256(hashJSON)
+	return hex.EncodeToString(sum[:])
+}
+
+// unchanged checks the hash annotation of the live object and that the keys and labels
+// we own were not modified by something else since the last write
+func unchanged(live metav1.Object, liveData map[string]string, hash string, data map[string]string, meta objectMeta) bool {
+	if live.GetAnnotations()[dataHashAnnotation] != hash {
+		return false
+	}
+	for key, value := range data {
+		if liveValue, ok := liveData[key]; !ok || liveValue != value {
+			return false
+		}
+	}
+	liveLabels := live.GetLabels()
+	for key, value := range meta.labels {
+		if liveValue, ok := liveLabels[key]; !ok || liveValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+func entriesCount(results *ldap.SearchResult) int {
+	if results == nil {
+		return 0
+	}
+	return len(results.Entries)
+}
+
+// getOwnerReference returns the owner of objects written to the namespace,
+// nil if no owner is configured or the owner does not exist in the namespace
+func getOwnerReference(clientset kubernetes.Interface, config *config.Config, namespace string, logger *slog.Logger) (*metav1.OwnerReference, error) {
+	if config.OwnerReference == "" {
+		return nil, nil
+	}
+	kind, name, _ := strings.Cut(config.OwnerReference, "/")
+	var uid types.UID
+	var err error
+	switch kind {
+	case "Deployment":
+		var deployment *appsv1.Deployment
+		if deployment, err = clientset.AppsV1().Deployments(namespace).Get(context.TODO(), name, metav1.GetOptions{}); err == nil {
+			uid = deployment.UID
+		}
+	case "StatefulSet":
+		var statefulSet *appsv1.StatefulSet
+		if statefulSet, err = clientset.AppsV1().StatefulSets(namespace).Get(context.TODO(), name, metav1.GetOptions{}); err == nil {
+			uid = statefulSet.UID
+		}
+	case "DaemonSet":
+		var daemonSet *appsv1.DaemonSet
+		if daemonSet, err = clientset.AppsV1().DaemonSets(namespace).Get(context.TODO(), name, metav1.GetOptions{}); err == nil {
+			uid = daemonSet.UID
+		}
+	}
+	if k8errors.IsNotFound(err) {
+		logger.Debug("Owner not found in namespace, not setting owner reference", "owner", config.OwnerReference, "namespace", namespace)
+		return nil, nil
+	} else if err != nil {
+		logger.Error("Error getting owner", "owner", config.OwnerReference, "namespace", namespace, "err", err)
+		return nil, err
+	}
+	return &metav1.OwnerReference{
+		APIVersion: appsv1.SchemeGroupVersion.String(),
+		Kind:       kind,
+		Name:       name,
+		UID:        uid,
+	}, nil
+}
+
+func ownerReferenceApply(owner *metav1.OwnerReference) *metav1ac.OwnerReferenceApplyConfiguration {
+	return metav1ac.OwnerReference().
+		WithAPIVersion(owner.APIVersion).
+		WithKind(owner.Kind).
+		WithName(owner.Name).
+		WithUID(owner.UID)
+}
+
+func applyOptions(force bool) metav1.ApplyOptions {
+	return metav1.ApplyOptions{
+		FieldManager: fieldManager,
+		Force:        force,
+	}
+}
+
+// applyError logs a failed apply, conflicts with fields owned by other
+// field managers are counted separately as they will not resolve on retry
+func applyError(kind string, namespace string, name string, err error, logger *slog.Logger) {
+	if k8errors.IsConflict(err) {
+		metrics.MetricConflictsTotal.WithLabelValues(name).Inc()
+		logger.Error(fmt.Sprintf("Conflict applying %s, fields are owned by another field manager, use --force-conflicts to take ownership", kind),
+			"name", name, "namespace", namespace, "field_manager", fieldManager, "err", err)
+		return
+	}
+	logger.Error(fmt.Sprintf("Failed to sync %s", kind), "action", "apply", "name", name, "namespace", namespace, "err", err)
+}
+
+// registerMappers registers the attribute, template and automount mappers defined by flags
+func registerMappers() {
+	attributeMappers, _ := parseAttributeMappers()
+	mapper.RegisterAttributeMappers(attributeMappers)
+	templateMappers, _ := parseTemplateMappers()
+	mapper.RegisterTemplateMappers(templateMappers)
+	mapper.RegisterAutomountMappers(splitList(*automountMaps), splitList(*automountUserMaps))
+}
+
 func createConfig() *config.Config {
 	userAttrMap := utils.AttrMap(*ldapUserAttrMap)
 	groupAttrMap := utils.AttrMap(*ldapGroupAttrMap)
-	enabledMappers := strings.Split(*mappersArg, ",")
+	attributeMappers, _ := parseAttributeMappers()
+	templateMappers, _ := parseTemplateMappers()
+	automountMapsList := splitList(*automountMaps)
+	enabledMappers := getEnabledMappers(attributeMappers, templateMappers, automountMapsList)
 	requiredUserAttrs := mapper.RequiredAttrs("user", enabledMappers)
 	requiredGroupAttrs := mapper.RequiredAttrs("group", enabledMappers)
+	if *customResources {
+		requiredUserAttrs = appendMissing(requiredUserAttrs, mapper.LDAPUserRequiredAttrs...)
+		requiredGroupAttrs = appendMissing(requiredGroupAttrs, mapper.LDAPGroupRequiredAttrs...)
+		for _, attr := range mapper.LDAPUserOptionalAttrs {
+			if _, ok := userAttrMap[attr]; ok {
+				requiredUserAttrs = appendMissing(requiredUserAttrs, attr)
+			}
+		}
+	}
+	if *openshiftGroups {
+		requiredUserAttrs = appendMissing(requiredUserAttrs, mapper.OpenShiftUserRequiredAttrs...)
+		requiredGroupAttrs = appendMissing(requiredGroupAttrs, mapper.OpenShiftGroupRequiredAttrs...)
+	}
+	for _, attr := range mapper.OptionalAttrs("user", enabledMappers, userAttrMap) {
+		if !utils.SliceContains(requiredUserAttrs, attr) {
+			requiredUserAttrs = append(requiredUserAttrs, attr)
+		}
+	}
+	for _, attr := range mapper.OptionalAttrs("group", enabledMappers, groupAttrMap) {
+		if !utils.SliceContains(requiredGroupAttrs, attr) {
+			requiredGroupAttrs = append(requiredGroupAttrs, attr)
+		}
+	}
 	mappersUserFilterMap := utils.AttrMap(*mappersUserFilter)
 	mappersGroupFilterMap := utils.AttrMap(*mappersGroupFilter)
+	mappersUserAttrMap, _ := parseMapperAttrMaps(*mapperUserAttrMaps)
+	mappersGroupAttrMap, _ := parseMapperAttrMaps(*mapperGroupAttrMaps)
+	userLDAPAttrs := mapper.RequiredLDAPAttrs("user", enabledMappers)
+	for _, attrMap := range mappersUserAttrMap {
+		for _, attr := range attrMap {
+			if !utils.SliceContains(userLDAPAttrs, attr) {
+				userLDAPAttrs = append(userLDAPAttrs, attr)
+			}
+		}
+	}
+	groupLDAPAttrs := mapper.RequiredLDAPAttrs("group", enabledMappers)
+	for _, attrMap := range mappersGroupAttrMap {
+		for _, attr := range attrMap {
+			if !utils.SliceContains(groupLDAPAttrs, attr) {
+				groupLDAPAttrs = append(groupLDAPAttrs, attr)
+			}
+		}
+	}
 	return &config.Config{
-		LdapURL:            *ldapURL,
-		LdapTLS:            *ldapTLS,
-		LdapTLSVerify:      *ldapTLSVerify,
-		LdapTLSCACert:      *ldapTLSCACert,
-		BindDN:             *ldapBindDN,
-		BindPassword:       *ldapBindPassword,
-		UserBaseDN:         *ldapUserBaseDN,
-		GroupBaseDN:        *ldapGroupBaseDN,
-		UserFilter:         *ldapUserFilter,
-		GroupFilter:        *ldapGroupFilter,
-		UserAttrMap:        userAttrMap,
-		GroupAttrMap:       groupAttrMap,
-		RequiredUserAttrs:  requiredUserAttrs,
-		RequiredGroupAttrs: requiredGroupAttrs,
-		PagedSearch:        *ldapPagedSearch,
-		PagedSearchSize:    *ldapPagedSearchSize,
-		MemberScheme:       *ldapMemberScheme,
-		UserPrefix:         *userPrefix,
-		EnabledMappers:     enabledMappers,
-		MappersUserFilter:  mappersUserFilterMap,
-		MappersGroupFilter: mappersGroupFilterMap,
+		LdapURL:                *ldapURL,
+		LdapTLS:                *ldapTLS,
+		LdapTLSVerify:          *ldapTLSVerify,
+		LdapTLSCACert:          *ldapTLSCACert,
+		BindDN:                 *ldapBindDN,
+		BindPassword:           *ldapBindPassword,
+		UserBaseDN:             *ldapUserBaseDN,
+		GroupBaseDN:            *ldapGroupBaseDN,
+		UserFilter:             *ldapUserFilter,
+		GroupFilter:            *ldapGroupFilter,
+		NetgroupBaseDN:         *ldapNetgroupBaseDN,
+		NetgroupFilter:         *ldapNetgroupFilter,
+		SudoersBaseDN:          *ldapSudoersBaseDN,
+		SudoersFilter:          *ldapSudoersFilter,
+		AutomountBaseDN:        *ldapAutomountBaseDN,
+		AutomountMaps:          automountMapsList,
+		AutomountUserMaps:      splitList(*automountUserMaps),
+		UserAttrMap:            userAttrMap,
+		GroupAttrMap:           groupAttrMap,
+		RequiredUserAttrs:      requiredUserAttrs,
+		RequiredGroupAttrs:     requiredGroupAttrs,
+		UserLDAPAttrs:          userLDAPAttrs,
+		GroupLDAPAttrs:         groupLDAPAttrs,
+		PagedSearch:            *ldapPagedSearch,
+		PagedSearchSize:        *ldapPagedSearchSize,
+		MemberScheme:           *ldapMemberScheme,
+		NestedGroups:           *ldapNestedGroups,
+		NestedGroupsDepth:      *ldapNestedGroupsDepth,
+		NestedGroupsInChain:    *ldapNestedGroupsChain,
+		UserPrefix:             *userPrefix,
+		SSHKeysFormat:          *sshKeysFormat,
+		EnabledMappers:         enabledMappers,
+		MappersUserAttrMap:     mappersUserAttrMap,
+		MappersGroupAttrMap:    mappersGroupAttrMap,
+		MappersUserPrefix:      utils.AttrMap(*mappersUserPrefix),
+		AttributeMappers:       attributeMappers,
+		TemplateMappers:        templateMappers,
+		MappersUserFilter:      mappersUserFilterMap,
+		MappersGroupFilter:     mappersGroupFilterMap,
+		SecretMappers:          splitList(*secretMappers),
+		ShardMappers:           splitList(*shardMappers),
+		ShardMaxSize:           *shardMaxSize,
+		Namespaces:             splitList(*namespace),
+		NamespaceSelector:      *namespaceSelector,
+		ConfigMapNames:         utils.AttrMap(*configMapNames),
+		ConfigMapPrefix:        *configMapPrefix,
+		ConfigMapSuffix:        *configMapSuffix,
+		Labels:                 utils.AttrMap(*configMapLabels),
+		Annotations:            utils.AttrMap(*configMapAnnotations),
+		OwnerReference:         *ownerReference,
+		Instance:               *instance,
+		Prune:                  *pruneMode,
+		ForceConflicts:         *forceConflicts,
+		CustomResources:        *customResources,
+		OpenShiftGroups:        *openshiftGroups,
+		KyvernoGlobalContext:   *kyvernoGlobalContext,
+		KyvernoNamespace:       getKyvernoNamespace(),
+		KyvernoRefreshInterval: *kyvernoRefresh,
+		Sink:                   *sinkType,
+		SinkDir:                *sinkDir,
+		SinkFormat:             *sinkFormat,
+		GitPath:                *gitPath,
+		GitRemote:              *gitRemote,
+		GitBranch:              *gitBranch,
+		GitUsername:            *gitUsername,
+		GitPassword:            *gitPassword,
+		GitAuthorName:          *gitAuthorName,
+		GitAuthorEmail:         *gitAuthorEmail,
 	}
 }
 
-func validateArgs(logger *slog.Logger) error {
+// parseAttributeMappers parses the attribute mapper definitions, returning
+// the valid definitions and any errors found
+func parseAttributeMappers() ([]config.AttributeMapper, []string) {
+	attributeMappers := []config.AttributeMapper{}
+	errs := []string{}
+	names := []string{}
+	for _, definition := range *attributeMappersArg {
+		attributeMapper, err := mapper.ParseAttributeMapper(definition)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("attribute-mapper=\"%s\"", err.Error()))
+			continue
+		}
+		if utils.SliceContains(names, attributeMapper.Name) {
+			errs = append(errs, fmt.Sprintf("attribute-mapper=\"Attribute mapper %s is defined more than once\"", attributeMapper.Name))
+			continue
+		}
+		names = append(names, attributeMapper.Name)
+		attributeMappers = append(attributeMappers, attributeMapper)
+	}
+	return attributeMappers, errs
+}
+
+// parseTemplateMappers parses the template mapper definitions, returning
+// the valid definitions and any errors found
+func parseTemplateMappers() ([]config.TemplateMapper, []string) {
+	templateMappers := []config.TemplateMapper{}
 	errs := []string{}
-	validMappers := mapper.ValidMappers()
-	enabledMappers := strings.Split(*mappersArg, ",")
-	userAttrs := mapper.RequiredAttrs("user", enabledMappers)
-	groupAttrs := mapper.RequiredAttrs("group", enabledMappers)
+	names := []string{}
+	for _, definition := range *templateMappersArg {
+		templateMapper, err := mapper.ParseTemplateMapper(definition)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("template-mapper=\"%s\"", err.Error()))
+			continue
+		}
+		if utils.SliceContains(names, templateMapper.Name) {
+			errs = append(errs, fmt.Sprintf("template-mapper=\"Template mapper %s is defined more than once\"", templateMapper.Name))
+			continue
+		}
+		names = append(names, templateMapper.Name)
+		templateMappers = append(templateMappers, templateMapper)
+	}
+	return templateMappers, errs
+}
+
+// splitList splits a comma separated list ignoring empty values
+func splitList(list string) []string {
+	values := []string{}
+	for _, value := range strings.Split(list, ",") {
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// parseMapperAttrMaps parses the mapper specific attribute maps, each defined as <mapper>=<attr map>
+func parseMapperAttrMaps(defs []string) (map[string]map[string]string, []string) {
+	attrMaps := make(map[string]map[string]string)
+	errs := []string{}
+	for _, def := range defs {
+		name, attrMap, ok := strings.Cut(def, "=")
+		if !ok || name == "" || attrMap == "" {
+			errs = append(errs, fmt.Sprintf("Mapper attribute map '%s' invalid, must be <mapper>=<attr map>", def))
+			continue
+		}
+		attrMaps[name] = utils.AttrMap(attrMap)
+	}
+	return attrMaps, errs
+}
+
+// appendMissing appends the values not already in the slice
+func appendMissing(slice []string, values ...string) []string {
+	for _, value := range values {
+		if !utils.SliceContains(slice, value) {
+			slice = append(slice, value)
+		}
+	}
+	return slice
+}
+
+// isShardName returns true when name is the <base>-index or a <base>-<N> shard name of base
+func isShardName(name string, base string) bool {
+	suffix, ok := strings.CutPrefix(name, base+"-")
+	if !ok || suffix == "" {
+		return false
+	}
+	if suffix == "index" {
+		return true
+	}
+	for _, c := range suffix {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// mapperUserFilter returns the user filter of a mapper, defaulting to the global user filter
+func mapperUserFilter(config *config.Config, mapperName string) string {
+	if filter, ok := config.MappersUserFilter[mapperName]; ok {
+		return filter
+	}
+	return config.UserFilter
+}
+
+// getKyvernoNamespace returns the namespace of ConfigMaps referenced by Kyverno, defaulting to the only namespace written to
+func getKyvernoNamespace() string {
+	if *kyvernoNamespace != "" {
+		return *kyvernoNamespace
+	}
+	if namespaces := splitList(*namespace); len(namespaces) == 1 && *namespaceSelector == "" {
+		return namespaces[0]
+	}
+	return ""
+}
+
+// getEnabledMappers returns the mappers enabled with the mappers flag along with every defined attribute, template and automount mapper
+func getEnabledMappers(attributeMappers []config.AttributeMapper, templateMappers []config.TemplateMapper, automountMaps []string) []string {
+	enabledMappers := splitList(*mappersArg)
+	for _, attributeMapper := range attributeMappers {
+		if !utils.SliceContains(enabledMappers, attributeMapper.Name) {
+			enabledMappers = append(enabledMappers, attributeMapper.Name)
+		}
+	}
+	for _, templateMapper := range templateMappers {
+		if !utils.SliceContains(enabledMappers, templateMapper.Name) {
+			enabledMappers = append(enabledMappers, templateMapper.Name)
+		}
+	}
+	for _, automountMap := range automountMaps {
+		if !utils.SliceContains(enabledMappers, automountMap) {
+			enabledMappers = append(enabledMappers, automountMap)
+		}
+	}
+	return enabledMappers
+}
+
+func validateArgs(logger *slog.Logger) error {
+	// Register the defined mappers in a copy of the registry so validation does not change global state
+	registry := mapper.DefaultRegistry().Clone()
+	attributeMappers, errs := parseAttributeMappers()
+	registry.RegisterAttributeMappers(attributeMappers)
+	templateMappers, templateErrs := parseTemplateMappers()
+	errs = append(errs, templateErrs...)
+	registry.RegisterTemplateMappers(templateMappers)
+	automountMapsList := []string{}
+	for _, automountMap := range splitList(*automountMaps) {
+		if err := mapper.ValidateAutomountMap(automountMap); err != nil {
+			errs = append(errs, fmt.Sprintf("automount-maps=\"%s\"", err.Error()))
+			continue
+		}
+		automountMapsList = append(automountMapsList, automountMap)
+	}
+	for _, automountMap := range splitList(*automountUserMaps) {
+		if !utils.SliceContains(automountMapsList, automountMap) {
+			errs = append(errs, fmt.Sprintf("automount-user-maps=\"Automount map %s is not defined in automount maps\"", automountMap))
+		}
+	}
+	if len(automountMapsList) > 0 && *ldapAutomountBaseDN == "" {
+		errs = append(errs, "ldap-automount-base-dn=\"Must provide LDAP automount Base DN when automount maps are defined\"")
+	}
+	registry.RegisterAutomountMappers(automountMapsList, splitList(*automountUserMaps))
+	validMappers := registry.ValidMappers()
+	enabledMappers := getEnabledMappers(attributeMappers, templateMappers, automountMapsList)
+	userAttrs := registry.RequiredAttrs("user", enabledMappers)
+	groupAttrs := registry.RequiredAttrs("group", enabledMappers)
+	if *customResources {
+		userAttrs = appendMissing(userAttrs, mapper.LDAPUserRequiredAttrs...)
+		groupAttrs = appendMissing(groupAttrs, mapper.LDAPGroupRequiredAttrs...)
+	}
+	if *openshiftGroups {
+		userAttrs = appendMissing(userAttrs, mapper.OpenShiftUserRequiredAttrs...)
+		groupAttrs = appendMissing(groupAttrs, mapper.OpenShiftGroupRequiredAttrs...)
+	}
 	var err error
 	if len(groupAttrs) > 0 {
 		groupAttrMap := utils.AttrMap(*ldapGroupAttrMap)
@@ -326,22 +1454,194 @@ func validateArgs(logger *slog.Logger) error {
 	if !utils.SliceContains(validLdapMemberScheme, *ldapMemberScheme) {
 		errs = append(errs, fmt.Sprintf("ldap-member-scheme=\"LDAP member scheme '%s' invalid\"", *ldapMemberScheme))
 	}
-	for _, mapper := range strings.Split(*mappersArg, ",") {
-		if !utils.SliceContains(validMappers, mapper) {
-			errs = append(errs, fmt.Sprintf("mappers=\"Defined mapper %s is not valid\"", mapper))
+	if *ldapNestedGroups && *ldapMemberScheme == "memberuid" {
+		errs = append(errs, "ldap-nested-groups=\"Nested groups are not supported with memberuid member scheme\"")
+	}
+	if *ldapNestedGroupsDepth < 0 {
+		errs = append(errs, fmt.Sprintf("ldap-nested-groups-depth=\"Nested groups depth %d must not be negative\"", *ldapNestedGroupsDepth))
+	}
+	if *ldapNestedGroupsChain && !*ldapNestedGroups {
+		errs = append(errs, "ldap-nested-groups-in-chain=\"Must enable nested groups to use in chain matching rule\"")
+	}
+	if mapper.NetgroupsRequired(enabledMappers) && *ldapNetgroupBaseDN == "" {
+		errs = append(errs, "ldap-netgroup-base-dn=\"Must provide LDAP Netgroup Base DN when netgroup mappers are enabled\"")
+	}
+	if !utils.SliceContains(mapper.ValidSSHKeysFormats, *sshKeysFormat) {
+		errs = append(errs, fmt.Sprintf("ssh-keys-format=\"SSH keys format '%s' invalid\"", *sshKeysFormat))
+	}
+	if mapper.SudoRolesRequired(enabledMappers) && *ldapSudoersBaseDN == "" {
+		errs = append(errs, "ldap-sudoers-base-dn=\"Must provide LDAP sudoers Base DN when the user-sudo mapper is enabled\"")
+	}
+	enabledNames := mapper.MapperNames(enabledMappers)
+	validNames := []string{}
+	for i, definition := range enabledMappers {
+		name, mapperType := mapper.ParseMapperInstance(definition)
+		if !utils.SliceContains(validMappers, mapperType) {
+			errs = append(errs, fmt.Sprintf("mappers=\"Defined mapper %s is not valid\"", mapperType))
+		} else {
+			validNames = append(validNames, name)
+		}
+		if name != mapperType {
+			for _, msg := range validation.IsDNS1123Label(name) {
+				errs = append(errs, fmt.Sprintf("mappers=\"Mapper instance name '%s' invalid: %s\"", name, msg))
+			}
+		}
+		if utils.SliceContains(enabledNames[:i], name) {
+			errs = append(errs, fmt.Sprintf("mappers=\"Mapper name %s is defined more than once\"", name))
+		}
+	}
+	mapperUserAttrMapsMap, mapperAttrMapErrs := parseMapperAttrMaps(*mapperUserAttrMaps)
+	for _, err := range mapperAttrMapErrs {
+		errs = append(errs, fmt.Sprintf("mapper-user-attr-map=\"%s\"", err))
+	}
+	for name := range mapperUserAttrMapsMap {
+		if !utils.SliceContains(enabledNames, name) {
+			errs = append(errs, fmt.Sprintf("mapper-user-attr-map=\"Defined mapper %s is not enabled\"", name))
+		}
+	}
+	mapperGroupAttrMapsMap, mapperAttrMapErrs := parseMapperAttrMaps(*mapperGroupAttrMaps)
+	for _, err := range mapperAttrMapErrs {
+		errs = append(errs, fmt.Sprintf("mapper-group-attr-map=\"%s\"", err))
+	}
+	for name := range mapperGroupAttrMapsMap {
+		if !utils.SliceContains(enabledNames, name) {
+			errs = append(errs, fmt.Sprintf("mapper-group-attr-map=\"Defined mapper %s is not enabled\"", name))
+		}
+	}
+	for _, name := range utils.MapKeysStrings(utils.AttrMap(*mappersUserPrefix)) {
+		if !utils.SliceContains(enabledNames, name) {
+			errs = append(errs, fmt.Sprintf("mappers-user-prefix=\"Defined mapper %s is not enabled\"", name))
+		}
+	}
+	if *sinkType != "kubernetes" {
+		if *sinkDir == "" {
+			errs = append(errs, fmt.Sprintf("sink-dir=\"Must provide sink directory with %s sink\"", *sinkType))
+		}
+		if *customResources {
+			errs = append(errs, fmt.Sprintf("custom-resources=\"Not supported with %s sink\"", *sinkType))
+		}
+		if *openshiftGroups {
+			errs = append(errs, fmt.Sprintf("openshift-groups=\"Not supported with %s sink\"", *sinkType))
+		}
+		if *pruneMode != "none" {
+			errs = append(errs, fmt.Sprintf("prune=\"Not supported with %s sink\"", *sinkType))
+		}
+		if *ownerReference != "" {
+			errs = append(errs, fmt.Sprintf("owner-reference=\"Not supported with %s sink\"", *sinkType))
+		}
+		if *kyvernoGlobalContext {
+			errs = append(errs, fmt.Sprintf("kyverno-global-context=\"Not supported with %s sink\"", *sinkType))
+		}
+	} else if len(enabledMappers) > 0 && len(splitList(*namespace)) == 0 && *namespaceSelector == "" {
+		errs = append(errs, "namespace=\"Must provide namespace or namespace selector\"")
+	}
+	if *kyvernoGlobalContext && *sinkType == "kubernetes" {
+		if kyvernoNS := getKyvernoNamespace(); kyvernoNS == "" {
+			errs = append(errs, "kyverno-namespace=\"Must provide Kyverno namespace when writing to more than one namespace\"")
+		} else if *namespaceSelector == "" && !utils.SliceContains(splitList(*namespace), kyvernoNS) {
+			errs = append(errs, fmt.Sprintf("kyverno-namespace=\"Kyverno namespace %s must be one of the namespaces given with namespace\"", kyvernoNS))
+		}
+	}
+	if *kyvernoRefresh <= 0 {
+		errs = append(errs, "kyverno-refresh-interval=\"Must be greater than zero\"")
+	}
+	if *sinkType == "git" {
+		if *secretMappers != "" {
+			errs = append(errs, "secret-mappers=\"Not supported with git sink\"")
+		}
+		if *shardMappers != "" {
+			errs = append(errs, "shard-mappers=\"Not supported with git sink\"")
+		}
+		if *gitBranch == "" {
+			errs = append(errs, "git-branch=\"Must provide git branch\"")
+		}
+	}
+	if _, err := labels.Parse(*namespaceSelector); err != nil {
+		errs = append(errs, fmt.Sprintf("namespace-selector=\"Namespace selector '%s' invalid: %s\"", *namespaceSelector, err.Error()))
+	}
+	for _, mapper := range splitList(*secretMappers) {
+		if !utils.SliceContains(enabledNames, mapper) {
+			errs = append(errs, fmt.Sprintf("secret-mappers=\"Defined mapper %s is not enabled\"", mapper))
+		}
+	}
+	for _, mapper := range splitList(*shardMappers) {
+		if !utils.SliceContains(enabledNames, mapper) {
+			errs = append(errs, fmt.Sprintf("shard-mappers=\"Defined mapper %s is not enabled\"", mapper))
+		}
+	}
+	if *shardMaxSize <= 0 {
+		errs = append(errs, fmt.Sprintf("shard-max-size=\"Shard max size %d must be greater than 0\"", *shardMaxSize))
+	}
+	configMapNamesMap := utils.AttrMap(*configMapNames)
+	for _, mapper := range utils.MapKeysStrings(configMapNamesMap) {
+		if !utils.SliceContains(enabledNames, mapper) {
+			errs = append(errs, fmt.Sprintf("configmap-names=\"Defined mapper %s is not enabled\"", mapper))
+		}
+	}
+	names := registry.ConfigMapNames(&config.Config{
+		EnabledMappers:  enabledMappers,
+		ConfigMapNames:  configMapNamesMap,
+		ConfigMapPrefix: *configMapPrefix,
+		ConfigMapSuffix: *configMapSuffix,
+	}, logger)
+	nameMappers := make(map[string]string)
+	mapperNames := utils.MapKeysStrings(names)
+	sort.Strings(mapperNames)
+	for _, mapperName := range mapperNames {
+		name := names[mapperName]
+		if other, ok := nameMappers[name]; ok {
+			errs = append(errs, fmt.Sprintf("configmap-names=\"ConfigMap name %s of mapper %s is also used by mapper %s\"", name, mapperName, other))
+		}
+		nameMappers[name] = mapperName
+		// The index and shards of every mapper are read and deleted when sharding changes so they must not be used by other mappers
+		for _, otherName := range mapperNames {
+			if otherName != mapperName && isShardName(names[otherName], name) {
+				errs = append(errs, fmt.Sprintf("configmap-names=\"ConfigMap name %s of mapper %s collides with the index or shards of mapper %s\"", names[otherName], otherName, mapperName))
+			}
+		}
+		if utils.SliceContains(splitList(*shardMappers), mapperName) {
+			name = fmt.Sprintf("%s-index", name)
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			errs = append(errs, fmt.Sprintf("configmap-names=\"ConfigMap name '%s' of mapper %s invalid: %s\"", name, mapperName, msg))
+		}
+	}
+	for key, value := range utils.AttrMap(*configMapLabels) {
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, fmt.Sprintf("configmap-labels=\"Label key '%s' invalid: %s\"", key, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(value) {
+			errs = append(errs, fmt.Sprintf("configmap-labels=\"Label value '%s' invalid: %s\"", value, msg))
+		}
+	}
+	for key := range utils.AttrMap(*configMapAnnotations) {
+		for _, msg := range validation.IsQualifiedName(key) {
+			errs = append(errs, fmt.Sprintf("configmap-annotations=\"Annotation key '%s' invalid: %s\"", key, msg))
+		}
+	}
+	for _, msg := range validation.IsValidLabelValue(*instance) {
+		errs = append(errs, fmt.Sprintf("instance=\"Instance '%s' invalid: %s\"", *instance, msg))
+	}
+	if *instance == "" {
+		errs = append(errs, "instance=\"Instance must not be empty\"")
+	}
+	if *ownerReference != "" {
+		kind, name, _ := strings.Cut(*ownerReference, "/")
+		if !utils.SliceContains(validOwnerKinds, kind) || name == "" {
+			errs = append(errs, fmt.Sprintf("owner-reference=\"Owner reference '%s' invalid, must be <kind>/<name> with kind one of %s\"", *ownerReference, strings.Join(validOwnerKinds, ", ")))
 		}
 	}
 	mappersUserFilterMap := utils.AttrMap(*mappersUserFilter)
 	mappersUserFilterMapKeys := utils.MapKeysStrings(mappersUserFilterMap)
 	for _, mapper := range mappersUserFilterMapKeys {
-		if !utils.SliceContains(validMappers, mapper) {
+		if !utils.SliceContains(validMappers, mapper) && !utils.SliceContains(validNames, mapper) {
 			errs = append(errs, fmt.Sprintf("mappers-user-filter=\"Defined mapper %s is not valid for mappers filters users\"", mapper))
 		}
 	}
 	mappersGroupFilterMap := utils.AttrMap(*mappersGroupFilter)
 	mappersGroupFilterMapKeys := utils.MapKeysStrings(mappersGroupFilterMap)
 	for _, mapper := range mappersGroupFilterMapKeys {
-		if !utils.SliceContains(validMappers, mapper) {
+		if !utils.SliceContains(validMappers, mapper) && !utils.SliceContains(validNames, mapper) {
 			errs = append(errs, fmt.Sprintf("mappers-group-filter=\"Defined mapper %s is not valid for mappers filters groups\"", mapper))
 		}
 	}
@@ -15,9 +15,12 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -25,13 +28,25 @@ import (
 	"github.com/OSC/k8-ldap-configmap/internal/mapper"
 	"github.com/OSC/k8-ldap-configmap/internal/metrics"
 	"github.com/OSC/k8-ldap-configmap/internal/test"
+	"github.com/OSC/k8-ldap-configmap/internal/utils"
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/common/promslog"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	k8errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/validation/path"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 const (
@@ -70,7 +85,7 @@ func TestMain(m *testing.M) {
 }
 
 func clientset() kubernetes.Interface {
-	clientset := fake.NewSimpleClientset(&v1.Namespace{
+	clientset := fake.NewClientset(&v1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: "test",
 		},
@@ -97,7 +112,7 @@ func TestRun(t *testing.T) {
 	clientset := clientset()
 	config := createConfig()
 	mappers := mapper.GetMappers(config, logger)
-	err := run(mappers, config, clientset, logger)
+	err := run(mappers, config, clientset, nil, logger)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -136,8 +151,8 @@ func TestRun(t *testing.T) {
 	k8_ldap_configmap_errors_total{mapper="user-uid"} 0
 	# HELP k8_ldap_configmap_keys_count Number of data keys in ConfigMap
 	# TYPE k8_ldap_configmap_keys_count gauge
-	k8_ldap_configmap_keys_count{configmap="user-gid-map"} 3
-	k8_ldap_configmap_keys_count{configmap="user-uid-map"} 3
+	k8_ldap_configmap_keys_count{configmap="user-gid-map",namespace="test"} 3
+	k8_ldap_configmap_keys_count{configmap="user-uid-map",namespace="test"} 3
 	`
 
 	if err := testutil.GatherAndCompare(metrics.MetricGathers(false), strings.NewReader(expected),
@@ -163,7 +178,7 @@ func TestRunGroups(t *testing.T) {
 	clientset := clientset()
 	config := createConfig()
 	mappers := mapper.GetMappers(config, logger)
-	err := run(mappers, config, clientset, logger)
+	err := run(mappers, config, clientset, nil, logger)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -234,8 +249,8 @@ func TestRunGroups(t *testing.T) {
 	k8_ldap_configmap_errors_total{mapper="user-groups"} 0
 	# HELP k8_ldap_configmap_keys_count Number of data keys in ConfigMap
 	# TYPE k8_ldap_configmap_keys_count gauge
-	k8_ldap_configmap_keys_count{configmap="user-gids-map"} 4
-	k8_ldap_configmap_keys_count{configmap="user-groups-map"} 3
+	k8_ldap_configmap_keys_count{configmap="user-gids-map",namespace="test"} 4
+	k8_ldap_configmap_keys_count{configmap="user-groups-map",namespace="test"} 3
 	`
 
 	if err := testutil.GatherAndCompare(metrics.MetricGathers(false), strings.NewReader(expected),
@@ -245,6 +260,1366 @@ func TestRunGroups(t *testing.T) {
 	}
 }
 
+func TestRunPasswd(t *testing.T) {
+	args := []string{
+		"--mappers=passwd,group",
+		"--ldap-user-attr-map=name=uid,uid=uidNumber,gid=gidNumber,home=homeDirectory,gecos=cn",
+	}
+	args = append(args, baseArgs...)
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	resetCounters()
+	clientset := clientset()
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	err := run(mappers, config, clientset, nil, logger)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	passwdMap, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "passwd-map", metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error getting passwd-map configmap: %v", err)
+		return
+	}
+	expected := "testuser1:x:1000:1001:testuser1::\ntestuser2:x:1001:1001:testuser2::\ntestuser3:x:1002:1000:testuser3::\n"
+	if val, ok := passwdMap.Data["passwd"]; !ok {
+		t.Errorf("Configmap is missing passwd")
+	} else if val != expected {
+		t.Errorf("Configmap value for passwd is incorrect: %q", val)
+	}
+	groupMap, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "group-map", metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error getting group-map configmap: %v", err)
+		return
+	}
+	expected = "testgroup1:x:1001:testuser1,testuser2\ntestgroup2:x:1000:testuser1,testuser2,testuser3\ntestgroup4:x:1003:\n"
+	if val, ok := groupMap.Data["group"]; !ok {
+		t.Errorf("Configmap is missing group")
+	} else if val != expected {
+		t.Errorf("Configmap value for group is incorrect: %q", val)
+	}
+}
+
+func TestRunAttributeMapper(t *testing.T) {
+	args := []string{
+		"--mappers=",
+		"--attribute-mapper=name=user-status,value=status",
+		"--attribute-mapper=name=gid-objectclass,entity=group,key=gidNumber,value=objectClass,output=json,configmap=gid-objectclass",
+	}
+	args = append(args, baseArgs...)
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		*attributeMappersArg = nil
+		mapper.RegisterAttributeMappers(nil)
+	}()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := validateArgs(logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if utils.SliceContains(mapper.ValidMappers(), "user-status") {
+		t.Errorf("Validation should not register mappers")
+	}
+	registerMappers()
+
+	resetCounters()
+	clientset := clientset()
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	if len(mappers) != 2 {
+		t.Fatalf("Unexpected number of mappers, got: %d", len(mappers))
+	}
+	err := run(mappers, config, clientset, nil, logger)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	statusMap, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-status-map", metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error getting user-status-map configmap: %v", err)
+		return
+	}
+	if val, ok := statusMap.Data["testuser1"]; !ok {
+		t.Errorf("Configmap is missing testuser1")
+	} else if val != "ACTIVE" {
+		t.Errorf("Configmap value for testuser1 is incorrect: %s", val)
+	}
+	objectClassMap, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "gid-objectclass", metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error getting gid-objectclass configmap: %v", err)
+		return
+	}
+	if val, ok := objectClassMap.Data["1001"]; !ok {
+		t.Errorf("Configmap is missing 1001")
+	} else if val != "[\"posixGroup\"]" {
+		t.Errorf("Configmap value for 1001 is incorrect: %s", val)
+	}
+}
+
+func TestRunTemplateMapper(t *testing.T) {
+	args := []string{
+		"--mappers=",
+		`--template-mapper={"name":"user-project","value":"/fs/ess/{{.gid}}/{{.name}}"}`,
+	}
+	args = append(args, baseArgs...)
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		*templateMappersArg = nil
+		mapper.RegisterTemplateMappers(nil)
+	}()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := validateArgs(logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	registerMappers()
+
+	resetCounters()
+	clientset := clientset()
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	err := run(mappers, config, clientset, nil, logger)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	projectMap, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-project-map", metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error getting user-project-map configmap: %v", err)
+		return
+	}
+	if val, ok := projectMap.Data["testuser1"]; !ok {
+		t.Errorf("Configmap is missing testuser1")
+	} else if val != "/fs/ess/1001/testuser1" {
+		t.Errorf("Configmap value for testuser1 is incorrect: %s", val)
+	}
+}
+
+func TestRunNetgroups(t *testing.T) {
+	args := []string{
+		"--mappers=user-uid,netgroup-members,user-netgroups",
+		fmt.Sprintf("--ldap-netgroup-base-dn=%s", test.NetgroupBaseDN),
+		fmt.Sprintf("--ldap-netgroup-filter=%s", test.NetgroupFilter),
+	}
+	args = append(args, baseArgs...)
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := validateArgs(logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	resetCounters()
+	clientset := clientset()
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	err := run(mappers, config, clientset, nil, logger)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	netgroupMap, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "netgroup-members-map", metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error getting netgroup-members-map configmap: %v", err)
+		return
+	}
+	if val, ok := netgroupMap.Data["netgroup1"]; !ok {
+		t.Errorf("Configmap is missing netgroup1")
+	} else if val != `["testuser1","testuser2","testuser3"]` {
+		t.Errorf("Configmap value for netgroup1 is incorrect: %s", val)
+	}
+	userNetgroupsMap, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-netgroups-map", metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error getting user-netgroups-map configmap: %v", err)
+		return
+	}
+	if val, ok := userNetgroupsMap.Data["testuser3"]; !ok {
+		t.Errorf("Configmap is missing testuser3")
+	} else if val != `["netgroup1","netgroup2"]` {
+		t.Errorf("Configmap value for testuser3 is incorrect: %s", val)
+	}
+	uidMap, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-uid-map", metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error getting user-uid-map configmap: %v", err)
+		return
+	}
+	if val, ok := uidMap.Data["testuser1"]; !ok || val != "1000" {
+		t.Errorf("Configmap value for testuser1 is incorrect: %s", val)
+	}
+}
+
+func TestRunSudo(t *testing.T) {
+	args := []string{
+		"--mappers=user-sudo",
+		fmt.Sprintf("--ldap-sudoers-base-dn=%s", test.SudoersBaseDN),
+		fmt.Sprintf("--ldap-sudoers-filter=%s", test.SudoersFilter),
+	}
+	args = append(args, baseArgs...)
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := validateArgs(logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	resetCounters()
+	clientset := clientset()
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	err := run(mappers, config, clientset, nil, logger)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	sudoMap, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-sudo-map", metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error getting user-sudo-map configmap: %v", err)
+		return
+	}
+	expected := `[{"name":"debug","hosts":["ALL"],"commands":["/usr/bin/kubectl debug"],"runAsUsers":["root"]}]`
+	if val, ok := sudoMap.Data["testuser1"]; !ok {
+		t.Errorf("Configmap is missing testuser1")
+	} else if val != expected {
+		t.Errorf("Configmap value for testuser1 is incorrect: %s", val)
+	}
+	if _, ok := sudoMap.Data["testuser4"]; ok {
+		t.Errorf("Configmap should not contain testuser4")
+	}
+}
+
+func TestRunAutomount(t *testing.T) {
+	args := []string{
+		"--mappers=",
+		"--user-prefix=user-",
+		fmt.Sprintf("--ldap-automount-base-dn=%s", test.AutomountBaseDN),
+		"--automount-maps=auto.home,auto.project",
+		"--automount-user-maps=auto.home",
+	}
+	args = append(args, baseArgs...)
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		*userPrefix = ""
+		*automountMaps = ""
+		*automountUserMaps = ""
+		mapper.RegisterAutomountMappers(nil, nil)
+	}()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := validateArgs(logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	registerMappers()
+
+	resetCounters()
+	clientset := clientset()
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	err := run(mappers, config, clientset, nil, logger)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	homeMap, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "auto.home-map", metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error getting auto.home-map configmap: %v", err)
+		return
+	}
+	if val, ok := homeMap.Data["user-testuser1"]; !ok {
+		t.Errorf("Configmap is missing user-testuser1")
+	} else if val != "nfs1:/home/testuser1" {
+		t.Errorf("Configmap value for user-testuser1 is incorrect: %s", val)
+	}
+	projectMap, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "auto.project-map", metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error getting auto.project-map configmap: %v", err)
+		return
+	}
+	if len(projectMap.Data) != 2 {
+		t.Errorf("Unexpected number of keys in auto.project-map, got: %d", len(projectMap.Data))
+	}
+	if val, ok := projectMap.Data["project1"]; !ok {
+		t.Errorf("Configmap is missing project1")
+	} else if val != "nfs3:/project/project1" {
+		t.Errorf("Configmap value for project1 is incorrect: %s", val)
+	}
+}
+
+func TestRunSecret(t *testing.T) {
+	args := []string{
+		"--mappers=user-uid,user-gid",
+		"--secret-mappers=user-uid",
+	}
+	args = append(args, baseArgs...)
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		*secretMappers = ""
+	}()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := validateArgs(logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	resetCounters()
+	clientset := clientset()
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	err := run(mappers, config, clientset, nil, logger)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	uidSecret, err := clientset.CoreV1().Secrets("test").Get(context.TODO(), "user-uid-map", metav1.GetOptions{})
+	if err != nil {
+		t.Errorf("Unexpected error getting user-uid-map secret: %v", err)
+		return
+	}
+	if val, ok := uidSecret.Data["testuser1"]; !ok {
+		t.Errorf("Secret is missing testuser1")
+	} else if string(val) != "1000" {
+		t.Errorf("Secret value for testuser1 is incorrect: %s", string(val))
+	}
+	if _, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-uid-map", metav1.GetOptions{}); err == nil {
+		t.Errorf("Expected user-uid-map ConfigMap to not exist")
+	}
+	if _, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-gid-map", metav1.GetOptions{}); err != nil {
+		t.Errorf("Unexpected error getting user-gid-map configmap: %v", err)
+	}
+	if val := testutil.ToFloat64(metrics.MetricConfigMapKeys.WithLabelValues("user-uid-map", "test")); val != 3 {
+		t.Errorf("Unexpected keys count for user-uid-map, got: %v", val)
+	}
+	err = run(mappers, config, clientset, nil, logger)
+	if err != nil {
+		t.Errorf("Unexpected error updating: %v", err)
+	}
+}
+
+func TestRunUnchanged(t *testing.T) {
+	args := []string{
+		"--mappers=user-uid",
+	}
+	args = append(args, baseArgs...)
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	resetCounters()
+	metrics.MetricWritesTotal.Reset()
+	clientset := clientset()
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	for i := 0; i < 2; i++ {
+		if err := run(mappers, config, clientset, nil, logger); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	userUIDMap, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-uid-map", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error getting configmap: %v", err)
+	}
+	if val := userUIDMap.Annotations[dataHashAnnotation]; len(val) != 64 {
+		t.Errorf("Unexpected data hash annotation, got: %s", val)
+	}
+	userUIDMap.Data["testuser1"] = "9999"
+	if _, err := clientset.CoreV1().ConfigMaps("test").Update(context.TODO(), userUIDMap, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Unexpected error updating configmap: %v", err)
+	}
+	// The manual edit owns testuser1 so force taking it back
+	config.ForceConflicts = true
+	if err := run(mappers, config, clientset, nil, logger); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	userUIDMap, err = clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-uid-map", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error getting configmap: %v", err)
+	}
+	if val := userUIDMap.Data["testuser1"]; val != "1000" {
+		t.Errorf("Expected modified value to be restored, got: %s", val)
+	}
+
+	expected := `
+	# HELP k8_ldap_configmap_writes_total Total number of ConfigMap writes by action, either create, update or unchanged
+	# TYPE k8_ldap_configmap_writes_total counter
+	k8_ldap_configmap_writes_total{action="create",configmap="user-uid-map"} 1
+	k8_ldap_configmap_writes_total{action="unchanged",configmap="user-uid-map"} 1
+	k8_ldap_configmap_writes_total{action="update",configmap="user-uid-map"} 1
+	`
+	if err := testutil.GatherAndCompare(metrics.MetricGathers(false), strings.NewReader(expected),
+		"k8_ldap_configmap_writes_total"); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func TestRunMetadata(t *testing.T) {
+	args := []string{
+		"--mappers=user-uid,user-gid",
+		"--secret-mappers=user-gid",
+		"--configmap-labels=team=hpc,app.kubernetes.io/part-of=ldap",
+		"--configmap-annotations=argocd.argoproj.io/compare-options=IgnoreExtraneous",
+		"--owner-reference=Deployment/k8-ldap-configmap",
+	}
+	args = append(args, baseArgs...)
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		*secretMappers = ""
+		*configMapLabels = ""
+		*configMapAnnotations = ""
+		*ownerReference = ""
+	}()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := validateArgs(logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	resetCounters()
+	clientset := fake.NewClientset(&v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+	}, &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "k8-ldap-configmap", Namespace: "test", UID: "1234"},
+	})
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	err := run(mappers, config, clientset, nil, logger)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	userUIDMap, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-uid-map", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error getting configmap: %v", err)
+	}
+	userGIDSecret, err := clientset.CoreV1().Secrets("test").Get(context.TODO(), "user-gid-map", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error getting secret: %v", err)
+	}
+	expectedLabels := map[string]string{
+		"team":                      "hpc",
+		"app.kubernetes.io/part-of": "ldap",
+		managedByLabel:              appName,
+	}
+	for key, value := range expectedLabels {
+		if val := userUIDMap.Labels[key]; val != value {
+			t.Errorf("Unexpected configmap label %s, got: %s", key, val)
+		}
+		if val := userGIDSecret.Labels[key]; val != value {
+			t.Errorf("Unexpected secret label %s, got: %s", key, val)
+		}
+	}
+	if val := userUIDMap.Labels[mapperLabel]; val != "user-uid" {
+		t.Errorf("Unexpected mapper label, got: %s", val)
+	}
+	if val := userGIDSecret.Labels[mapperLabel]; val != "user-gid" {
+		t.Errorf("Unexpected secret mapper label, got: %s", val)
+	}
+	expectedAnnotations := map[string]string{
+		"argocd.argoproj.io/compare-options": "IgnoreExtraneous",
+		ldapURLAnnotation:                    "ldap://127.0.0.1:10389",
+		userEntriesAnnotation:                "3",
+		groupEntriesAnnotation:               "0",
+	}
+	for key, value := range expectedAnnotations {
+		if val := userUIDMap.Annotations[key]; val != value {
+			t.Errorf("Unexpected configmap annotation %s, got: %s", key, val)
+		}
+	}
+	if _, err := time.Parse(time.RFC3339, userUIDMap.Annotations[lastSyncAnnotation]); err != nil {
+		t.Errorf("Unexpected last sync time annotation: %v", err)
+	}
+	if len(userUIDMap.OwnerReferences) != 1 {
+		t.Fatalf("Unexpected owner references: %v", userUIDMap.OwnerReferences)
+	}
+	owner := userUIDMap.OwnerReferences[0]
+	if owner.Kind != "Deployment" || owner.Name != "k8-ldap-configmap" || owner.UID != "1234" || owner.APIVersion != "apps/v1" {
+		t.Errorf("Unexpected owner reference: %v", owner)
+	}
+	if len(userGIDSecret.OwnerReferences) != 1 {
+		t.Errorf("Unexpected secret owner references: %v", userGIDSecret.OwnerReferences)
+	}
+
+	lastSync := userUIDMap.Annotations[lastSyncAnnotation]
+	metrics.MetricWritesTotal.Reset()
+	metrics.MetricLastSync.Reset()
+	clientset.ClearActions()
+	if err := run(mappers, config, clientset, nil, logger); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if val := testutil.ToFloat64(metrics.MetricWritesTotal.WithLabelValues("user-uid-map", "unchanged")); val != 1 {
+		t.Errorf("Expected user-uid-map to be unchanged, got: %v", val)
+	}
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "patch" {
+			t.Errorf("Unexpected write of unchanged object: %v", action)
+		}
+	}
+	userUIDMap, err = clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-uid-map", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error getting configmap: %v", err)
+	}
+	if val := userUIDMap.Annotations[lastSyncAnnotation]; val != lastSync {
+		t.Errorf("Expected last sync time annotation to only change with the data, got: %s", val)
+	}
+	if val := testutil.ToFloat64(metrics.MetricLastSync.WithLabelValues("user-uid")); val == 0 {
+		t.Errorf("Expected last sync time metric to be set")
+	}
+}
+
+func TestRunPrune(t *testing.T) {
+	args := []string{
+		"--mappers=user-uid,user-gid,user-home",
+		"--secret-mappers=user-home",
+	}
+	args = append(args, baseArgs...)
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		*secretMappers = ""
+		*pruneMode = "none"
+	}()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	resetCounters()
+	metrics.MetricPrunedTotal.Reset()
+	clientset := fake.NewClientset(&v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+	}, &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "other-instance-map",
+			Namespace: "test",
+			Labels: map[string]string{
+				managedByLabel: appName,
+				instanceLabel:  "other",
+				mapperLabel:    "user-gid",
+			},
+		},
+	})
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	if err := run(mappers, config, clientset, nil, logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	args = []string{
+		"--mappers=user-uid",
+		"--secret-mappers=",
+		"--prune=dry-run",
+	}
+	args = append(args, baseArgs...)
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	config = createConfig()
+	mappers = mapper.GetMappers(config, logger)
+	if err := run(mappers, config, clientset, nil, logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-gid-map", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected user-gid-map to not be pruned in dry run: %v", err)
+	}
+	if val := testutil.ToFloat64(metrics.MetricPrunedTotal.WithLabelValues("user-gid-map", "dry-run")); val != 1 {
+		t.Errorf("Unexpected dry run pruned count for user-gid-map, got: %v", val)
+	}
+
+	*pruneMode = "delete"
+	config = createConfig()
+	if err := run(mappers, config, clientset, nil, logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-gid-map", metav1.GetOptions{}); !k8errors.IsNotFound(err) {
+		t.Errorf("Expected user-gid-map to be pruned, got: %v", err)
+	}
+	if _, err := clientset.CoreV1().Secrets("test").Get(context.TODO(), "user-home-map", metav1.GetOptions{}); !k8errors.IsNotFound(err) {
+		t.Errorf("Expected user-home-map secret to be pruned, got: %v", err)
+	}
+	if _, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-uid-map", metav1.GetOptions{}); err != nil {
+		t.Errorf("Unexpected error getting user-uid-map: %v", err)
+	}
+	if _, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "other-instance-map", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected ConfigMap of other instance to not be pruned: %v", err)
+	}
+	if val := testutil.ToFloat64(metrics.MetricPrunedTotal.WithLabelValues("user-home-map", "delete")); val != 1 {
+		t.Errorf("Unexpected pruned count for user-home-map, got: %v", val)
+	}
+}
+
+func TestRunCustomResources(t *testing.T) {
+	args := []string{
+		"--mappers=user-uid",
+		"--custom-resources",
+	}
+	args = append(args, baseArgs...)
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		*customResources = false
+	}()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	resetCounters()
+	metrics.MetricCustomResourceWritesTotal.Reset()
+	clientset := fake.NewClientset(&v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+	})
+	staleUser := &unstructured.Unstructured{}
+	staleUser.SetAPIVersion("ldap.osc.edu/v1alpha1")
+	staleUser.SetKind("LDAPUser")
+	staleUser.SetName("olduser")
+	staleUser.SetLabels(map[string]string{managedByLabel: appName, instanceLabel: "k8-ldap-configmap"})
+	otherUser := &unstructured.Unstructured{}
+	otherUser.SetAPIVersion("ldap.osc.edu/v1alpha1")
+	otherUser.SetKind("LDAPUser")
+	otherUser.SetName("otheruser")
+	otherUser.SetLabels(map[string]string{managedByLabel: appName, instanceLabel: "other"})
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		ldapUserGVR:  "LDAPUserList",
+		ldapGroupGVR: "LDAPGroupList",
+	}, staleUser, otherUser)
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	if err := run(mappers, config, clientset, dynamicClient, logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	user, err := dynamicClient.Resource(ldapUserGVR).Get(context.TODO(), "testuser1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error getting LDAPUser: %v", err)
+	}
+	if uid, _, _ := unstructured.NestedInt64(user.Object, "spec", "uid"); uid != 1000 {
+		t.Errorf("Unexpected uid, got: %v", uid)
+	}
+	if groups, _, _ := unstructured.NestedStringSlice(user.Object, "spec", "groups"); strings.Join(groups, ",") != "testgroup1,testgroup2" {
+		t.Errorf("Unexpected groups, got: %v", groups)
+	}
+	group, err := dynamicClient.Resource(ldapGroupGVR).Get(context.TODO(), "testgroup2", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error getting LDAPGroup: %v", err)
+	}
+	if members, _, _ := unstructured.NestedStringSlice(group.Object, "spec", "members"); strings.Join(members, ",") != "testuser1,testuser2,testuser3" {
+		t.Errorf("Unexpected members, got: %v", members)
+	}
+	if _, err := dynamicClient.Resource(ldapUserGVR).Get(context.TODO(), "olduser", metav1.GetOptions{}); !k8errors.IsNotFound(err) {
+		t.Errorf("Expected olduser to be deleted, got: %v", err)
+	}
+	if _, err := dynamicClient.Resource(ldapUserGVR).Get(context.TODO(), "otheruser", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected LDAPUser of other instance to not be deleted: %v", err)
+	}
+	if val := testutil.ToFloat64(metrics.MetricCustomResourceWritesTotal.WithLabelValues("LDAPUser", "create")); val != 3 {
+		t.Errorf("Unexpected LDAPUser create count, got: %v", val)
+	}
+	if val := testutil.ToFloat64(metrics.MetricCustomResourceWritesTotal.WithLabelValues("LDAPGroup", "create")); val != 3 {
+		t.Errorf("Unexpected LDAPGroup create count, got: %v", val)
+	}
+
+	if err := run(mappers, config, clientset, dynamicClient, logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if val := testutil.ToFloat64(metrics.MetricCustomResourceWritesTotal.WithLabelValues("LDAPUser", "unchanged")); val != 3 {
+		t.Errorf("Unexpected LDAPUser unchanged count, got: %v", val)
+	}
+	if val := testutil.ToFloat64(metrics.MetricCustomResourceWritesTotal.WithLabelValues("LDAPUser", "update")); val != 0 {
+		t.Errorf("Unexpected LDAPUser update count, got: %v", val)
+	}
+}
+
+func TestRunOpenShiftGroups(t *testing.T) {
+	args := []string{
+		"--mappers=",
+		"--openshift-groups",
+		"--user-prefix=foo-",
+	}
+	for _, arg := range baseArgs {
+		if !strings.HasPrefix(arg, "--namespace=") {
+			args = append(args, arg)
+		}
+	}
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	*namespace = ""
+	defer func() {
+		*openshiftGroups = false
+		*userPrefix = ""
+		*mappersArg = "user-uid,user-gid"
+	}()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := validateArgs(logger); err != nil {
+		t.Fatalf("Unexpected error validating args: %v", err)
+	}
+
+	resetCounters()
+	metrics.MetricCustomResourceWritesTotal.Reset()
+	clientset := fake.NewClientset()
+	staleGroup := &unstructured.Unstructured{}
+	staleGroup.SetAPIVersion("user.openshift.io/v1")
+	staleGroup.SetKind("Group")
+	staleGroup.SetName("oldgroup")
+	staleGroup.SetLabels(map[string]string{managedByLabel: appName, instanceLabel: "k8-ldap-configmap"})
+	syncedGroup := &unstructured.Unstructured{}
+	syncedGroup.SetAPIVersion("user.openshift.io/v1")
+	syncedGroup.SetKind("Group")
+	syncedGroup.SetName("testgroup4")
+	syncedGroup.SetLabels(map[string]string{"openshift.io/ldap.host": "ldap"})
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		openshiftGroupGVR: "GroupList",
+	}, staleGroup, syncedGroup)
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	if len(mappers) != 0 {
+		t.Errorf("Unexpected mappers: %v", mappers)
+	}
+	if err := run(mappers, config, clientset, dynamicClient, logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	group, err := dynamicClient.Resource(openshiftGroupGVR).Get(context.TODO(), "testgroup2", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error getting Group: %v", err)
+	}
+	if users, _, _ := unstructured.NestedStringSlice(group.Object, "users"); strings.Join(users, ",") != "foo-testuser1,foo-testuser2,foo-testuser3" {
+		t.Errorf("Unexpected users, got: %v", users)
+	}
+	if group.GetLabels()[managedByLabel] != appName {
+		t.Errorf("Unexpected labels, got: %v", group.GetLabels())
+	}
+	if _, err := dynamicClient.Resource(openshiftGroupGVR).Get(context.TODO(), "oldgroup", metav1.GetOptions{}); !k8errors.IsNotFound(err) {
+		t.Errorf("Expected oldgroup to be deleted, got: %v", err)
+	}
+	synced, err := dynamicClient.Resource(openshiftGroupGVR).Get(context.TODO(), "testgroup4", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Expected Group not managed by this instance to not be deleted: %v", err)
+	}
+	if _, ok := synced.GetLabels()[managedByLabel]; ok {
+		t.Errorf("Expected Group not managed by this instance to not be updated")
+	}
+	if val := testutil.ToFloat64(metrics.MetricCustomResourceWritesTotal.WithLabelValues("Group", "create")); val != 2 {
+		t.Errorf("Unexpected Group create count, got: %v", val)
+	}
+	if val := testutil.ToFloat64(metrics.MetricCustomResourceWritesTotal.WithLabelValues("Group", "skipped")); val != 1 {
+		t.Errorf("Unexpected Group skipped count, got: %v", val)
+	}
+	if val := testutil.ToFloat64(metrics.MetricCustomResourceWritesTotal.WithLabelValues("Group", "delete")); val != 1 {
+		t.Errorf("Unexpected Group delete count, got: %v", val)
+	}
+
+	metrics.MetricCustomResourceWritesTotal.Reset()
+	groupObjects := map[string]map[string]interface{}{
+		"Domain_Admins": {"users": []interface{}{"foo-testuser1"}},
+		"bad/name":      {"users": []interface{}{"foo-testuser2"}},
+	}
+	if err := syncCustomResource(dynamicClient, config, openshiftGroupGVR, "Group", groupObjects, path.IsValidPathSegmentName, logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := dynamicClient.Resource(openshiftGroupGVR).Get(context.TODO(), "Domain_Admins", metav1.GetOptions{}); err != nil {
+		t.Errorf("Unexpected error getting Group Domain_Admins: %v", err)
+	}
+	if val := testutil.ToFloat64(metrics.MetricCustomResourceWritesTotal.WithLabelValues("Group", "skipped")); val != 1 {
+		t.Errorf("Unexpected Group skipped count for invalid name, got: %v", val)
+	}
+}
+
+func TestRunFilesystem(t *testing.T) {
+	dir := t.TempDir()
+	args := []string{
+		"--mappers=user-uid,user-home",
+		"--secret-mappers=user-home",
+		"--sink=filesystem",
+		fmt.Sprintf("--sink-dir=%s", dir),
+		"--sink-format=configmap",
+	}
+	for _, arg := range baseArgs {
+		if !strings.HasPrefix(arg, "--namespace=") {
+			args = append(args, arg)
+		}
+	}
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	*namespace = ""
+	defer func() {
+		*sinkType = "kubernetes"
+		*sinkDir = ""
+		*sinkFormat = "json"
+		*secretMappers = ""
+	}()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := validateArgs(logger); err != nil {
+		t.Fatalf("Unexpected error validating args: %v", err)
+	}
+
+	resetCounters()
+	metrics.MetricWritesTotal.Reset()
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	if err := run(mappers, config, nil, nil, logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "user-uid-map", "testuser1"))
+	if err != nil {
+		t.Fatalf("Unexpected error reading testuser1: %v", err)
+	}
+	if string(content) != "1000" {
+		t.Errorf("Unexpected value for testuser1, got: %s", string(content))
+	}
+	info, err := os.Stat(filepath.Join(dir, "user-home-map"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o700 {
+		t.Errorf("Unexpected permissions of secret mapper directory, got: %v", info.Mode().Perm())
+	}
+	if val := testutil.ToFloat64(metrics.MetricWritesTotal.WithLabelValues("user-uid-map", "create")); val != 1 {
+		t.Errorf("Unexpected create count, got: %v", val)
+	}
+
+	*sinkFormat = "json"
+	config = createConfig()
+	if err := run(mappers, config, nil, nil, logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := run(mappers, config, nil, nil, logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	content, err = os.ReadFile(filepath.Join(dir, "user-uid-map.json"))
+	if err != nil {
+		t.Fatalf("Unexpected error reading user-uid-map.json: %v", err)
+	}
+	data := make(map[string]string)
+	if err := json.Unmarshal(content, &data); err != nil {
+		t.Fatal(err)
+	}
+	if data["testuser2"] != "1001" {
+		t.Errorf("Unexpected value for testuser2, got: %s", data["testuser2"])
+	}
+	if val := testutil.ToFloat64(metrics.MetricWritesTotal.WithLabelValues("user-uid-map", "unchanged")); val != 1 {
+		t.Errorf("Unexpected unchanged count, got: %v", val)
+	}
+}
+
+func TestRunGit(t *testing.T) {
+	remoteDir := t.TempDir()
+	remote, err := git.PlainInit(remoteDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := []string{
+		"--mappers=user-uid,user-gid",
+		"--sink=git",
+		fmt.Sprintf("--sink-dir=%s", filepath.Join(t.TempDir(), "repo")),
+		"--git-path=ldap",
+		fmt.Sprintf("--git-remote=%s", remoteDir),
+	}
+	for _, arg := range baseArgs {
+		if !strings.HasPrefix(arg, "--namespace=") {
+			args = append(args, arg)
+		}
+	}
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	*namespace = ""
+	defer func() {
+		*sinkType = "kubernetes"
+		*sinkDir = ""
+		*gitPath = ""
+		*gitRemote = ""
+	}()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := validateArgs(logger); err != nil {
+		t.Fatalf("Unexpected error validating args: %v", err)
+	}
+
+	resetCounters()
+	metrics.MetricWritesTotal.Reset()
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	for i := 0; i < 2; i++ {
+		if err := run(mappers, config, nil, nil, logger); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	ref, err := remote.Reference(plumbing.NewBranchReferenceName("main"), true)
+	if err != nil {
+		t.Fatalf("Unexpected error getting remote branch: %v", err)
+	}
+	commit, err := remote.CommitObject(ref.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.NumParents() != 0 {
+		t.Errorf("Expected a single commit, got %d parents", commit.NumParents())
+	}
+	if !strings.Contains(commit.Message, "user-gid-map: added testuser1, testuser2, testuser3") {
+		t.Errorf("Unexpected commit message, got: %s", commit.Message)
+	}
+	file, err := commit.File("ldap/user-uid-map.yaml")
+	if err != nil {
+		t.Fatalf("Unexpected error getting user-uid-map.yaml: %v", err)
+	}
+	content, _ := file.Contents()
+	if !strings.Contains(content, "testuser1: \"1000\"") || !strings.Contains(content, mapperLabel+": user-uid") {
+		t.Errorf("Unexpected content of user-uid-map.yaml, got: %s", content)
+	}
+	if val := testutil.ToFloat64(metrics.MetricWritesTotal.WithLabelValues("user-uid-map", "unchanged")); val != 1 {
+		t.Errorf("Unexpected unchanged count, got: %v", val)
+	}
+}
+
+func TestRunKyverno(t *testing.T) {
+	args := []string{
+		"--mappers=user-uid,user-gid,user-home",
+		"--secret-mappers=user-home",
+		"--configmap-names=user-gid=gids",
+		"--kyverno-global-context",
+	}
+	args = append(args, baseArgs...)
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		*kyvernoGlobalContext = false
+		*secretMappers = ""
+		*configMapNames = ""
+	}()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := validateArgs(logger); err != nil {
+		t.Fatalf("Unexpected error validating args: %v", err)
+	}
+
+	resetCounters()
+	metrics.MetricCustomResourceWritesTotal.Reset()
+	clientset := fake.NewClientset(&v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+	})
+	staleEntry := &unstructured.Unstructured{}
+	staleEntry.SetAPIVersion("kyverno.io/v2alpha1")
+	staleEntry.SetKind("GlobalContextEntry")
+	staleEntry.SetName("user-groups")
+	staleEntry.SetLabels(map[string]string{managedByLabel: appName, instanceLabel: "k8-ldap-configmap"})
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		globalContextEntryGVR: "GlobalContextEntryList",
+	}, staleEntry)
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	if err := run(mappers, config, clientset, dynamicClient, logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	entry, err := dynamicClient.Resource(globalContextEntryGVR).Get(context.TODO(), "user-gid", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error getting GlobalContextEntry: %v", err)
+	}
+	if urlPath, _, _ := unstructured.NestedString(entry.Object, "spec", "apiCall", "urlPath"); urlPath != "/api/v1/namespaces/test/configmaps/gids" {
+		t.Errorf("Unexpected urlPath, got: %s", urlPath)
+	}
+	if refresh, _, _ := unstructured.NestedString(entry.Object, "spec", "apiCall", "refreshInterval"); refresh != "5m0s" {
+		t.Errorf("Unexpected refreshInterval, got: %s", refresh)
+	}
+	if _, err := dynamicClient.Resource(globalContextEntryGVR).Get(context.TODO(), "user-home", metav1.GetOptions{}); !k8errors.IsNotFound(err) {
+		t.Errorf("Expected no GlobalContextEntry for secret mapper, got: %v", err)
+	}
+	if _, err := dynamicClient.Resource(globalContextEntryGVR).Get(context.TODO(), "user-groups", metav1.GetOptions{}); !k8errors.IsNotFound(err) {
+		t.Errorf("Expected GlobalContextEntry of disabled mapper to be deleted, got: %v", err)
+	}
+	if val := testutil.ToFloat64(metrics.MetricCustomResourceWritesTotal.WithLabelValues("GlobalContextEntry", "create")); val != 2 {
+		t.Errorf("Unexpected GlobalContextEntry create count, got: %v", val)
+	}
+
+	config.SecretMappers = []string{"user-home", "user-gid"}
+	config.ShardMappers = []string{"user-uid"}
+	if err := run(mappers, config, clientset, dynamicClient, logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, name := range []string{"user-uid", "user-gid"} {
+		if _, err := dynamicClient.Resource(globalContextEntryGVR).Get(context.TODO(), name, metav1.GetOptions{}); !k8errors.IsNotFound(err) {
+			t.Errorf("Expected GlobalContextEntry %s moved to secret or shard mode to be deleted, got: %v", name, err)
+		}
+	}
+
+	config.KyvernoNamespace = "other"
+	if err := run(mappers, config, clientset, dynamicClient, logger); err == nil {
+		t.Errorf("Expected error when Kyverno namespace is not written to")
+	}
+}
+
+func TestRunConfigMapNames(t *testing.T) {
+	args := []string{
+		"--mappers=user-uid,user-gid",
+		"--configmap-names=user-uid=uids",
+		"--configmap-prefix=realm1-",
+		"--configmap-suffix=-v1",
+	}
+	args = append(args, baseArgs...)
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		*configMapNames = ""
+		*configMapPrefix = ""
+		*configMapSuffix = ""
+	}()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := validateArgs(logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	resetCounters()
+	clientset := clientset()
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	if err := run(mappers, config, clientset, nil, logger); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	for _, name := range []string{"realm1-uids-v1", "realm1-user-gid-map-v1"} {
+		if _, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), name, metav1.GetOptions{}); err != nil {
+			t.Errorf("Unexpected error getting configmap %s: %v", name, err)
+		}
+	}
+	if _, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-uid-map", metav1.GetOptions{}); err == nil {
+		t.Errorf("Expected user-uid-map to not exist")
+	}
+}
+
+func TestRunMapperInstances(t *testing.T) {
+	args := []string{
+		"--mappers=user-uid,active-groups:user-groups,all-groups:user-groups",
+		fmt.Sprintf("--mappers-group-filter=all-groups=%s", test.GroupFilter),
+		fmt.Sprintf("--mappers-user-filter=all-groups=%s", test.UserFilter),
+		"--mappers-user-prefix=all-groups=ldap-",
+		"--configmap-names=all-groups=groups-all",
+	}
+	args = append(args, baseArgs...)
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		*mappersGroupFilter = ""
+		*mappersUserFilter = ""
+		*mappersUserPrefix = ""
+		*configMapNames = ""
+	}()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := validateArgs(logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	resetCounters()
+	clientset := clientset()
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	if len(mappers) != 3 {
+		t.Fatalf("Unexpected number of mappers, got: %d", len(mappers))
+	}
+	if err := run(mappers, config, clientset, nil, logger); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	activeGroupsMap, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "active-groups-map", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error getting active-groups-map configmap: %v", err)
+	}
+	if len(activeGroupsMap.Data) != 3 {
+		t.Errorf("Unexpected number of items in active-groups-map configmap data: %d", len(activeGroupsMap.Data))
+	}
+	if val := activeGroupsMap.Labels[mapperLabel]; val != "active-groups" {
+		t.Errorf("Unexpected mapper label, got: %s", val)
+	}
+	allGroupsMap, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "groups-all", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error getting groups-all configmap: %v", err)
+	}
+	if len(allGroupsMap.Data) != 4 {
+		t.Errorf("Unexpected number of items in groups-all configmap data: %d", len(allGroupsMap.Data))
+	}
+	if val, ok := allGroupsMap.Data["ldap-testuser1"]; !ok {
+		t.Errorf("Configmap is missing ldap-testuser1")
+	} else if val != "[\"testgroup1\",\"testgroup2\",\"testgroup3\"]" {
+		t.Errorf("Configmap value for ldap-testuser1 is incorrect: %s", val)
+	}
+	if _, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-groups-map", metav1.GetOptions{}); err == nil {
+		t.Errorf("Expected user-groups-map to not exist")
+	}
+	if val := testutil.ToFloat64(metrics.MetricConfigMapKeys.WithLabelValues("groups-all", "test")); val != 4 {
+		t.Errorf("Unexpected keys count for groups-all, got: %v", val)
+	}
+	expected := `
+	# HELP k8_ldap_configmap_errors_total Total number of errors
+	# TYPE k8_ldap_configmap_errors_total counter
+	k8_ldap_configmap_errors_total{mapper="active-groups"} 0
+	k8_ldap_configmap_errors_total{mapper="all-groups"} 0
+	k8_ldap_configmap_errors_total{mapper="user-uid"} 0
+	`
+	if err := testutil.GatherAndCompare(metrics.MetricGathers(false), strings.NewReader(expected),
+		"k8_ldap_configmap_errors_total"); err != nil {
+		t.Errorf("unexpected collecting result:\n%s", err)
+	}
+}
+
+func TestRunConflicts(t *testing.T) {
+	args := []string{
+		"--mappers=user-uid",
+	}
+	args = append(args, baseArgs...)
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	resetCounters()
+	metrics.MetricConflictsTotal.Reset()
+	clientset := clientset()
+	other := corev1ac.ConfigMap("user-uid-map", "test").WithData(map[string]string{"testuser1": "9999"})
+	_, err := clientset.CoreV1().ConfigMaps("test").Apply(context.TODO(), other, metav1.ApplyOptions{FieldManager: "other"})
+	if err != nil {
+		t.Fatalf("Unexpected error applying configmap as other field manager: %v", err)
+	}
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	err = run(mappers, config, clientset, nil, logger)
+	if err == nil {
+		t.Errorf("Expected conflict error")
+	}
+	if val := testutil.ToFloat64(metrics.MetricConflictsTotal.WithLabelValues("user-uid-map")); val != 1 {
+		t.Errorf("Unexpected conflicts count, got: %v", val)
+	}
+	userUIDMap, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-uid-map", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error getting configmap: %v", err)
+	}
+	if val := userUIDMap.Data["testuser1"]; val != "9999" {
+		t.Errorf("Expected conflicting value to be left alone, got: %s", val)
+	}
+
+	config.ForceConflicts = true
+	err = run(mappers, config, clientset, nil, logger)
+	if err != nil {
+		t.Errorf("Unexpected error forcing conflicts: %v", err)
+	}
+	userUIDMap, err = clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-uid-map", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error getting configmap: %v", err)
+	}
+	if val := userUIDMap.Data["testuser1"]; val != "1000" {
+		t.Errorf("Unexpected value for testuser1 after forcing conflicts, got: %s", val)
+	}
+	if val := userUIDMap.Labels[managedByLabel]; val != appName {
+		t.Errorf("Unexpected managed-by label, got: %s", val)
+	}
+}
+
+func TestRunNamespaces(t *testing.T) {
+	args := []string{
+		"--mappers=user-uid",
+		"--namespace=test,other",
+		"--namespace-selector=tenant=true",
+	}
+	for _, arg := range baseArgs {
+		if !strings.HasPrefix(arg, "--namespace=") {
+			args = append(args, arg)
+		}
+	}
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		*namespace = "test"
+		*namespaceSelector = ""
+	}()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := validateArgs(logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	resetCounters()
+	metrics.MetricNamespaceErrorsTotal.Reset()
+	clientset := fake.NewClientset(&v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+	}, &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "tenant1", Labels: map[string]string{"tenant": "true"}},
+	}, &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "tenant2", Labels: map[string]string{"tenant": "true"}},
+	}, &v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "tenant3"},
+	})
+	clientset.PrependReactor("patch", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetNamespace() == "other" {
+			return true, nil, fmt.Errorf("namespace other is not writable")
+		}
+		return false, nil, nil
+	})
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	err := run(mappers, config, clientset, nil, logger)
+	if err == nil {
+		t.Errorf("Expected error writing to namespace other")
+	}
+	for _, ns := range []string{"test", "tenant1", "tenant2"} {
+		if _, err := clientset.CoreV1().ConfigMaps(ns).Get(context.TODO(), "user-uid-map", metav1.GetOptions{}); err != nil {
+			t.Errorf("Unexpected error getting configmap in namespace %s: %v", ns, err)
+		}
+	}
+	if _, err := clientset.CoreV1().ConfigMaps("tenant3").Get(context.TODO(), "user-uid-map", metav1.GetOptions{}); err == nil {
+		t.Errorf("Expected no configmap in namespace tenant3")
+	}
+	if val := testutil.ToFloat64(metrics.MetricNamespaceErrorsTotal.WithLabelValues("other")); val != 1 {
+		t.Errorf("Unexpected namespace errors for other, got: %v", val)
+	}
+	for _, ns := range []string{"test", "tenant1", "tenant2"} {
+		if val := testutil.ToFloat64(metrics.MetricConfigMapKeys.WithLabelValues("user-uid-map", ns)); val != 3 {
+			t.Errorf("Unexpected keys count for user-uid-map in namespace %s, got: %v", ns, val)
+		}
+	}
+
+	tenant3, _ := clientset.CoreV1().Namespaces().Get(context.TODO(), "tenant3", metav1.GetOptions{})
+	tenant3.Labels = map[string]string{"tenant": "true"}
+	if _, err := clientset.CoreV1().Namespaces().Update(context.TODO(), tenant3, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	_ = run(mappers, config, clientset, nil, logger)
+	if _, err := clientset.CoreV1().ConfigMaps("tenant3").Get(context.TODO(), "user-uid-map", metav1.GetOptions{}); err != nil {
+		t.Errorf("Unexpected error getting configmap in namespace tenant3: %v", err)
+	}
+}
+
+func TestShardData(t *testing.T) {
+	data := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		data[fmt.Sprintf("user%d", i)] = "1000"
+	}
+	shards := shardData(data, 200)
+	if len(shards) < 5 {
+		t.Errorf("Unexpected number of shards, got: %d", len(shards))
+	}
+	keys := 0
+	for i, shard := range shards {
+		size := 0
+		for key, value := range shard {
+			size += len(key) + len(value)
+			if shardKey(key, len(shards)) != i {
+				t.Errorf("Key %s in unexpected shard %d", key, i)
+			}
+		}
+		if size > 200 {
+			t.Errorf("Shard %d size %d exceeds max size", i, size)
+		}
+		keys += len(shard)
+	}
+	if keys != len(data) {
+		t.Errorf("Unexpected number of keys in shards, got: %d", keys)
+	}
+	if shards := shardData(data, 10000); len(shards) != 1 {
+		t.Errorf("Unexpected number of shards, got: %d", len(shards))
+	}
+	if shards := shardData(map[string]string{}, 10); len(shards) != 1 {
+		t.Errorf("Unexpected number of shards for empty data, got: %d", len(shards))
+	}
+}
+
+func TestRunShards(t *testing.T) {
+	args := []string{
+		"--mappers=user-uid,user-gid",
+		"--shard-mappers=user-uid",
+		"--shard-max-size=20",
+	}
+	args = append(args, baseArgs...)
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		*shardMappers = ""
+		*shardMaxSize = 921600
+	}()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	if err := validateArgs(logger); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	resetCounters()
+	clientset := clientset()
+	config := createConfig()
+	mappers := mapper.GetMappers(config, logger)
+	config.ShardMappers = nil
+	err := run(mappers, config, clientset, nil, logger)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-uid-map", metav1.GetOptions{}); err != nil {
+		t.Fatalf("Unexpected error getting user-uid-map before sharding: %v", err)
+	}
+
+	config.ShardMappers = []string{"user-uid"}
+	err = run(mappers, config, clientset, nil, logger)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	index, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-uid-map-index", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error getting user-uid-map-index configmap: %v", err)
+	}
+	shards, _ := strconv.Atoi(index.Data["shards"])
+	if shards < 2 {
+		t.Errorf("Unexpected number of shards, got: %d", shards)
+	}
+	keys := 0
+	for i := 0; i < shards; i++ {
+		shard, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), fmt.Sprintf("user-uid-map-%d", i), metav1.GetOptions{})
+		if err != nil {
+			t.Errorf("Unexpected error getting shard %d: %v", i, err)
+			continue
+		}
+		keys += len(shard.Data)
+	}
+	if keys != 3 {
+		t.Errorf("Unexpected number of keys across shards, got: %d", keys)
+	}
+	if _, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-uid-map", metav1.GetOptions{}); !k8errors.IsNotFound(err) {
+		t.Errorf("Expected unsharded user-uid-map to be deleted once sharded, got: %v", err)
+	}
+	if val := testutil.ToFloat64(metrics.MetricShards.WithLabelValues("user-uid-map")); int(val) != shards {
+		t.Errorf("Unexpected shards metric, got: %v", val)
+	}
+
+	config.ShardMaxSize = 921600
+	err = run(mappers, config, clientset, nil, logger)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if val := testutil.ToFloat64(metrics.MetricShards.WithLabelValues("user-uid-map")); val != 1 {
+		t.Errorf("Unexpected shards metric, got: %v", val)
+	}
+	for i := 1; i < shards; i++ {
+		if _, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), fmt.Sprintf("user-uid-map-%d", i), metav1.GetOptions{}); err == nil {
+			t.Errorf("Expected stale shard %d to be deleted", i)
+		}
+	}
+
+	config.ShardMappers = nil
+	err = run(mappers, config, clientset, nil, logger)
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), "user-uid-map", metav1.GetOptions{}); err != nil {
+		t.Errorf("Unexpected error getting user-uid-map after unsharding: %v", err)
+	}
+	for _, name := range []string{"user-uid-map-0", "user-uid-map-index"} {
+		if _, err := clientset.CoreV1().ConfigMaps("test").Get(context.TODO(), name, metav1.GetOptions{}); err == nil {
+			t.Errorf("Expected %s to be deleted after unsharding", name)
+		}
+	}
+
+	clientset.(*fake.Clientset).PrependReactor("get", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.(k8stesting.GetAction).GetName() == "user-uid-map-index" {
+			return true, nil, k8errors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "user-uid-map-index", fmt.Errorf("forbidden"))
+		}
+		return false, nil, nil
+	})
+	err = run(mappers, config, clientset, nil, logger)
+	if !k8errors.IsForbidden(err) {
+		t.Errorf("Expected error getting shard index to be returned, got: %v", err)
+	}
+}
+
 func resetCounters() {
 	metrics.MetricErrorsTotal.Reset()
 	metrics.MetricConfigMapSize.Reset()
@@ -275,9 +1650,32 @@ func TestValidateArgs(t *testing.T) {
 		"--ldap-user-attr-map=name=uid",
 		"--ldap-group-attr-map=name=cn",
 		"--ldap-member-scheme=foo",
-		"--mappers=user-uid,user-gid,user-groups,foobar",
+		"--ldap-nested-groups-depth=-1",
+		"--ldap-nested-groups-in-chain",
+		"--mappers=user-uid,user-gid,user-groups,user-netgroups,user-sudo,foobar,Bad_Name:user-gid,user-uid:user-uid,groups:foo",
+		"--ldap-netgroup-base-dn=",
+		"--ldap-sudoers-base-dn=",
+		"--ssh-keys-format=foo",
+		"--automount-maps=auto.home,user-uid",
+		"--automount-user-maps=auto.master",
+		"--ldap-automount-base-dn=",
+		"--secret-mappers=user-home",
+		"--namespace-selector=foo in (bar",
+		"--shard-mappers=user-home",
+		"--shard-max-size=0",
+		"--mapper-user-attr-map=user-groups",
+		"--mapper-group-attr-map=other=gid=gidNumber",
+		"--mappers-user-prefix=foo=bar",
 		"--mappers-user-filter=user-groups=(foobar=baz),foobar=(foobar=baz)",
 		"--mappers-group-filter=user-groups=(foobar=baz),foobar=(foobar=baz)",
+		"--attribute-mapper=name=user-shell",
+		"--attribute-mapper=name=user-uid,value=loginShell",
+		`--template-mapper={"name":"user-project","value":"{{.name"}`,
+		"--configmap-labels=bad key=foo,team=-bad-",
+		"--configmap-annotations=bad key=foo",
+		"--owner-reference=Pod/foo",
+		"--instance=-bad-",
+		"--configmap-names=user-uid=Invalid_Name,user-gid=user-groups-map,user-home=home,user-netgroups=user-groups-map-index,user-sudo=user-groups-map-1",
 	}...)
 	if _, err := kingpin.CommandLine.Parse(args); err != nil {
 		t.Errorf("Error parsing args %s", err.Error())
@@ -298,9 +1696,99 @@ func TestValidateArgs(t *testing.T) {
 	if !strings.Contains(err.Error(), "mappers") {
 		t.Errorf("Expected error about invalid mapper")
 	}
+	if !strings.Contains(err.Error(), "mappers=\"Defined mapper foo is not valid") {
+		t.Errorf("Expected error about invalid mapper type of instance")
+	}
+	if !strings.Contains(err.Error(), "mappers=\"Mapper instance name 'Bad_Name'") {
+		t.Errorf("Expected error about invalid mapper instance name")
+	}
+	if !strings.Contains(err.Error(), "mappers=\"Mapper name user-uid is defined more than once") {
+		t.Errorf("Expected error about duplicate mapper name")
+	}
+	if !strings.Contains(err.Error(), "mapper-user-attr-map=\"Mapper attribute map") {
+		t.Errorf("Expected error about invalid mapper user attribute map")
+	}
+	if !strings.Contains(err.Error(), "mapper-group-attr-map=\"Defined mapper other") {
+		t.Errorf("Expected error about mapper group attribute map of mapper not enabled")
+	}
+	if !strings.Contains(err.Error(), "mappers-user-prefix") {
+		t.Errorf("Expected error about user prefix of mapper not enabled")
+	}
 	if !strings.Contains(err.Error(), "ldap-member-scheme") {
 		t.Errorf("Expected error about invalid member scheme")
 	}
+	if !strings.Contains(err.Error(), "ldap-nested-groups-depth") {
+		t.Errorf("Expected error about negative nested groups depth")
+	}
+	if !strings.Contains(err.Error(), "ldap-nested-groups-in-chain") {
+		t.Errorf("Expected error about in chain without nested groups")
+	}
+	if !strings.Contains(err.Error(), "attribute-mapper") {
+		t.Errorf("Expected error about invalid attribute mapper")
+	}
+	if !strings.Contains(err.Error(), "template-mapper") {
+		t.Errorf("Expected error about invalid template mapper")
+	}
+	if !strings.Contains(err.Error(), "ldap-netgroup-base-dn") {
+		t.Errorf("Expected error about missing netgroup base DN")
+	}
+	if !strings.Contains(err.Error(), "ldap-sudoers-base-dn") {
+		t.Errorf("Expected error about missing sudoers base DN")
+	}
+	if !strings.Contains(err.Error(), "ssh-keys-format") {
+		t.Errorf("Expected error about invalid SSH keys format")
+	}
+	if !strings.Contains(err.Error(), "automount-maps") {
+		t.Errorf("Expected error about invalid automount map")
+	}
+	if !strings.Contains(err.Error(), "automount-user-maps") {
+		t.Errorf("Expected error about undefined automount user map")
+	}
+	if !strings.Contains(err.Error(), "ldap-automount-base-dn") {
+		t.Errorf("Expected error about missing automount base DN")
+	}
+	if !strings.Contains(err.Error(), "secret-mappers") {
+		t.Errorf("Expected error about secret mapper not enabled")
+	}
+	if !strings.Contains(err.Error(), "namespace-selector") {
+		t.Errorf("Expected error about invalid namespace selector")
+	}
+	if !strings.Contains(err.Error(), "shard-mappers") {
+		t.Errorf("Expected error about shard mapper not enabled")
+	}
+	if !strings.Contains(err.Error(), "shard-max-size") {
+		t.Errorf("Expected error about invalid shard max size")
+	}
+	if !strings.Contains(err.Error(), "configmap-labels=\"Label key") {
+		t.Errorf("Expected error about invalid label key")
+	}
+	if !strings.Contains(err.Error(), "configmap-labels=\"Label value") {
+		t.Errorf("Expected error about invalid label value")
+	}
+	if !strings.Contains(err.Error(), "configmap-annotations") {
+		t.Errorf("Expected error about invalid annotation key")
+	}
+	if !strings.Contains(err.Error(), "owner-reference") {
+		t.Errorf("Expected error about invalid owner reference")
+	}
+	if !strings.Contains(err.Error(), "configmap-names=\"ConfigMap name 'Invalid_Name'") {
+		t.Errorf("Expected error about invalid ConfigMap name")
+	}
+	if !strings.Contains(err.Error(), "configmap-names=\"Defined mapper user-home is not enabled") {
+		t.Errorf("Expected error about ConfigMap name of mapper not enabled")
+	}
+	if !strings.Contains(err.Error(), "configmap-names=\"ConfigMap name user-groups-map") {
+		t.Errorf("Expected error about duplicate ConfigMap name")
+	}
+	if !strings.Contains(err.Error(), "configmap-names=\"ConfigMap name user-groups-map-index of mapper user-netgroups collides with the index or shards of mapper user-groups") {
+		t.Errorf("Expected error about ConfigMap name colliding with an index")
+	}
+	if !strings.Contains(err.Error(), "configmap-names=\"ConfigMap name user-groups-map-1 of mapper user-sudo collides with the index or shards of mapper user-groups") {
+		t.Errorf("Expected error about ConfigMap name colliding with a shard")
+	}
+	if !strings.Contains(err.Error(), "instance=") {
+		t.Errorf("Expected error about invalid instance")
+	}
 	if !strings.Contains(err.Error(), "ldap-bind") {
 		t.Errorf("Expected error about missing bind args")
 	}
@@ -310,6 +1798,53 @@ func TestValidateArgs(t *testing.T) {
 	if !strings.Contains(err.Error(), "mappers-group-filter") {
 		t.Errorf("Expected error about incorrect mappers-group-filter")
 	}
+	args = []string{
+		"--ldap-url=ldap://ldap:389",
+		fmt.Sprintf("--ldap-group-base-dn=%s", test.GroupBaseDN),
+		fmt.Sprintf("--ldap-user-base-dn=%s", test.UserBaseDN),
+		"--namespace=",
+		"--namespace-selector=",
+	}
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Errorf("Error parsing args %s", err.Error())
+	}
+	err = validateArgs(promslog.NewNopLogger())
+	if err == nil || !strings.Contains(err.Error(), "namespace=") {
+		t.Errorf("Expected error about missing namespace")
+	}
+	args = []string{
+		"--ldap-url=ldap://ldap:389",
+		fmt.Sprintf("--ldap-group-base-dn=%s", test.GroupBaseDN),
+		fmt.Sprintf("--ldap-user-base-dn=%s", test.UserBaseDN),
+		"--namespace=test,other",
+		"--kyverno-global-context",
+		"--kyverno-namespace=kyverno",
+	}
+	if _, err := kingpin.CommandLine.Parse(args); err != nil {
+		t.Errorf("Error parsing args %s", err.Error())
+	}
+	defer func() {
+		*kyvernoGlobalContext = false
+		*kyvernoNamespace = ""
+		*namespace = "test"
+	}()
+	err = validateArgs(promslog.NewNopLogger())
+	if err == nil || !strings.Contains(err.Error(), "Kyverno namespace kyverno must be one of the namespaces") {
+		t.Errorf("Expected error about Kyverno namespace not written to")
+	}
+	*kyvernoNamespace = "other"
+	if err := validateArgs(promslog.NewNopLogger()); err != nil && strings.Contains(err.Error(), "kyverno-namespace=") {
+		t.Errorf("Unexpected Kyverno namespace error: %v", err)
+	}
+	// Namespaces from a selector are only known at run time
+	*kyvernoNamespace = "kyverno"
+	*namespaceSelector = "tenant=true"
+	defer func() {
+		*namespaceSelector = ""
+	}()
+	if err := validateArgs(promslog.NewNopLogger()); err != nil && strings.Contains(err.Error(), "kyverno-namespace=") {
+		t.Errorf("Unexpected Kyverno namespace error with namespace selector: %v", err)
+	}
 }
 
 func TestSetupLogging(t *testing.T) {
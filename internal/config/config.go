@@ -13,31 +13,99 @@
 
 package config
 
+import "time"
+
 var (
 	DefaultUserAttrMap  = "name=uid,uid=uidNumber,gid=gidNumber,home=homeDirectory"
 	DefaultGroupAttrMap = "name=cn,gid=gidNumber"
 )
 
 type Config struct {
-	LdapURL            string
-	LdapTLS            bool
-	LdapTLSVerify      bool
-	LdapTLSCACert      string
-	BindDN             string
-	BindPassword       string
-	UserBaseDN         string
-	GroupBaseDN        string
-	UserFilter         string
-	GroupFilter        string
-	UserAttrMap        map[string]string
-	GroupAttrMap       map[string]string
-	RequiredUserAttrs  []string
-	RequiredGroupAttrs []string
-	PagedSearch        bool
-	PagedSearchSize    int
-	MemberScheme       string
-	UserPrefix         string
-	EnabledMappers     []string
-	MappersUserFilter  map[string]string
-	MappersGroupFilter map[string]string
+	LdapURL                string
+	LdapTLS                bool
+	LdapTLSVerify          bool
+	LdapTLSCACert          string
+	BindDN                 string
+	BindPassword           string
+	UserBaseDN             string
+	GroupBaseDN            string
+	UserFilter             string
+	GroupFilter            string
+	NetgroupBaseDN         string
+	NetgroupFilter         string
+	SudoersBaseDN          string
+	SudoersFilter          string
+	AutomountBaseDN        string
+	AutomountMaps          []string
+	AutomountUserMaps      []string
+	UserAttrMap            map[string]string
+	GroupAttrMap           map[string]string
+	RequiredUserAttrs      []string
+	RequiredGroupAttrs     []string
+	PagedSearch            bool
+	PagedSearchSize        int
+	MemberScheme           string
+	NestedGroups           bool
+	NestedGroupsDepth      int
+	NestedGroupsInChain    bool
+	UserPrefix             string
+	SSHKeysFormat          string
+	EnabledMappers         []string
+	InstanceName           string
+	MappersUserAttrMap     map[string]map[string]string
+	MappersGroupAttrMap    map[string]map[string]string
+	MappersUserPrefix      map[string]string
+	MappersUserFilter      map[string]string
+	MappersGroupFilter     map[string]string
+	SecretMappers          []string
+	ShardMappers           []string
+	ShardMaxSize           int
+	Namespaces             []string
+	NamespaceSelector      string
+	ConfigMapNames         map[string]string
+	ConfigMapPrefix        string
+	ConfigMapSuffix        string
+	Labels                 map[string]string
+	Annotations            map[string]string
+	OwnerReference         string
+	Instance               string
+	Prune                  string
+	ForceConflicts         bool
+	CustomResources        bool
+	OpenShiftGroups        bool
+	KyvernoGlobalContext   bool
+	KyvernoNamespace       string
+	KyvernoRefreshInterval time.Duration
+	Sink                   string
+	SinkDir                string
+	SinkFormat             string
+	GitPath                string
+	GitRemote              string
+	GitBranch              string
+	GitUsername            string
+	GitPassword            string
+	GitAuthorName          string
+	GitAuthorEmail         string
+	AttributeMappers       []AttributeMapper
+	TemplateMappers        []TemplateMapper
+	UserLDAPAttrs          []string
+	GroupLDAPAttrs         []string
+}
+
+// AttributeMapper defines a mapper that maps one LDAP attribute to one or more other LDAP attributes
+type AttributeMapper struct {
+	Name          string
+	Entity        string
+	KeyAttr       string
+	ValueAttrs    []string
+	Output        string
+	ConfigMapName string
+}
+
+// TemplateMapper defines a mapper that builds keys and values for each user from Go text templates
+type TemplateMapper struct {
+	Name          string `json:"name"`
+	KeyTemplate   string `json:"key"`
+	ValueTemplate string `json:"value"`
+	ConfigMapName string `json:"configmap"`
 }
@@ -16,14 +16,23 @@ package ldap
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"fmt"
 	"log/slog"
 	"net"
 	"net/url"
+	"strings"
 
 	"github.com/OSC/k8-ldap-configmap/internal/config"
 	ldap "github.com/go-ldap/ldap/v3"
 )
 
+const (
+	// MatchingRuleInChain is the OID of the Active Directory LDAP_MATCHING_RULE_IN_CHAIN matching rule
+	MatchingRuleInChain = "1.2.840.113556.1.4.1941"
+	// AutomountFilter is the filter used to search for the entries of an automount map
+	AutomountFilter = "(objectClass=automount)"
+)
+
 func LDAPConnect(config *config.Config, logger *slog.Logger) (*ldap.Conn, error) {
 	logger.Debug("Connecting to LDAP", "url", config.LdapURL)
 	l, err := ldap.DialURL(config.LdapURL)
@@ -78,21 +87,75 @@ func LDAPTLS(l *ldap.Conn, config *config.Config, logger *slog.Logger) error {
 }
 
 func LDAPGroups(l *ldap.Conn, filter string, config *config.Config, logger *slog.Logger) (*ldap.SearchResult, error) {
+	return LDAPGroupsWithUserFilter(l, filter, config.UserFilter, config, logger)
+}
+
+// LDAPGroupsWithUserFilter searches for groups, when resolving nested groups in chain only
+// the users matching the user filter are returned as members
+func LDAPGroupsWithUserFilter(l *ldap.Conn, filter string, userFilter string, config *config.Config, logger *slog.Logger) (*ldap.SearchResult, error) {
 	attrs := []string{}
 	for _, a := range config.RequiredGroupAttrs {
 		attrs = append(attrs, config.GroupAttrMap[a])
 	}
+	attrs = append(attrs, config.GroupLDAPAttrs...)
 	switch config.MemberScheme {
 	case "member":
 		attrs = append(attrs, "member")
 	case "memberuid":
 		attrs = append(attrs, "memberuid")
+	case "memberof":
+		if config.NestedGroups && !config.NestedGroupsInChain {
+			attrs = append(attrs, "memberof")
+		}
 	}
 	logger.Debug("Running group search", "basedn", config.GroupBaseDN, "filter", config.GroupFilter)
 	request := ldap.NewSearchRequest(config.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
 		filter, attrs, nil)
 	result, err := LDAPSearch(l, request, "group", config, logger)
-	return result, err
+	if err != nil || !config.NestedGroups || !config.NestedGroupsInChain {
+		return result, err
+	}
+	for _, entry := range result.Entries {
+		members, err := LDAPGroupMembersInChain(l, entry.DN, userFilter, config, logger)
+		if err != nil {
+			return result, err
+		}
+		setAttributeValues(entry, "member", members)
+	}
+	return result, nil
+}
+
+// LDAPGroupMembersInChain returns the DNs of all users matching the user filter that are members of a group,
+// directly or through nested groups, using the Active Directory LDAP_MATCHING_RULE_IN_CHAIN matching rule.
+func LDAPGroupMembersInChain(l *ldap.Conn, groupDN string, userFilter string, config *config.Config, logger *slog.Logger) ([]string, error) {
+	filter := membersInChainFilter(groupDN, userFilter)
+	logger.Debug("Running group members in chain search", "basedn", config.UserBaseDN, "filter", filter)
+	request := ldap.NewSearchRequest(config.UserBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		filter, []string{"1.1"}, nil)
+	result, err := LDAPSearch(l, request, "group-members-in-chain", config, logger)
+	if err != nil {
+		return nil, err
+	}
+	members := []string{}
+	for _, entry := range result.Entries {
+		members = append(members, entry.DN)
+	}
+	return members, nil
+}
+
+// membersInChainFilter returns the filter of users matching the user filter that are members of the group in chain
+func membersInChainFilter(groupDN string, userFilter string) string {
+	return fmt.Sprintf("(&%s(memberOf:%s:=%s))", userFilter, MatchingRuleInChain, ldap.EscapeFilter(groupDN))
+}
+
+func setAttributeValues(entry *ldap.Entry, name string, values []string) {
+	for i, attr := range entry.Attributes {
+		if strings.EqualFold(attr.Name, name) {
+			entry.Attributes[i] = ldap.NewEntryAttribute(name, values)
+			return
+		}
+	}
+	entry.Attributes = append(entry.Attributes, ldap.NewEntryAttribute(name, values))
 }
 
 func LDAPUsers(l *ldap.Conn, filter string, config *config.Config, logger *slog.Logger) (*ldap.SearchResult, error) {
@@ -100,6 +163,7 @@ func LDAPUsers(l *ldap.Conn, filter string, config *config.Config, logger *slog.
 	for _, a := range config.RequiredUserAttrs {
 		attrs = append(attrs, config.UserAttrMap[a])
 	}
+	attrs = append(attrs, config.UserLDAPAttrs...)
 	if config.MemberScheme == "memberof" {
 		attrs = append(attrs, "memberof")
 	}
@@ -110,6 +174,37 @@ func LDAPUsers(l *ldap.Conn, filter string, config *config.Config, logger *slog.
 	return result, err
 }
 
+// LDAPNetgroups searches for nisNetgroup entries returning their name, triples and member netgroups
+func LDAPNetgroups(l *ldap.Conn, filter string, config *config.Config, logger *slog.Logger) (*ldap.SearchResult, error) {
+	attrs := []string{"cn", "nisNetgroupTriple", "memberNisNetgroup"}
+	logger.Debug("Running netgroup search", "basedn", config.NetgroupBaseDN, "filter", filter)
+	request := ldap.NewSearchRequest(config.NetgroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		filter, attrs, nil)
+	result, err := LDAPSearch(l, request, "netgroup", config, logger)
+	return result, err
+}
+
+// LDAPSudoRoles searches for sudoRole entries returning their name, users, hosts, commands and run as users
+func LDAPSudoRoles(l *ldap.Conn, filter string, config *config.Config, logger *slog.Logger) (*ldap.SearchResult, error) {
+	attrs := []string{"cn", "sudoUser", "sudoHost", "sudoCommand", "sudoRunAsUser"}
+	logger.Debug("Running sudoers search", "basedn", config.SudoersBaseDN, "filter", filter)
+	request := ldap.NewSearchRequest(config.SudoersBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		filter, attrs, nil)
+	result, err := LDAPSearch(l, request, "sudoers", config, logger)
+	return result, err
+}
+
+// LDAPAutomounts searches for the automount entries of an automount map
+func LDAPAutomounts(l *ldap.Conn, mapName string, config *config.Config, logger *slog.Logger) (*ldap.SearchResult, error) {
+	attrs := []string{"automountKey", "automountInformation"}
+	baseDN := fmt.Sprintf("automountMapName=%s,%s", ldap.EscapeDN(mapName), config.AutomountBaseDN)
+	logger.Debug("Running automount search", "basedn", baseDN, "filter", AutomountFilter)
+	request := ldap.NewSearchRequest(baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		AutomountFilter, attrs, nil)
+	result, err := LDAPSearch(l, request, "automount", config, logger)
+	return result, err
+}
+
 func LDAPSearch(l *ldap.Conn, request *ldap.SearchRequest, queryType string, config *config.Config, logger *slog.Logger) (*ldap.SearchResult, error) {
 	var result *ldap.SearchResult
 	var err error
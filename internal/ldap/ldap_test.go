@@ -16,11 +16,13 @@ package ldap
 import (
 	"fmt"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/OSC/k8-ldap-configmap/internal/config"
 	"github.com/OSC/k8-ldap-configmap/internal/test"
+	ldap "github.com/go-ldap/ldap/v3"
 	"github.com/prometheus/common/promslog"
 )
 
@@ -111,3 +113,31 @@ func TestLDAPConnectTLSError(t *testing.T) {
 		t.Errorf("Expected an error with invalid TLS ServerName")
 	}
 }
+
+func TestSetAttributeValues(t *testing.T) {
+	entry := ldap.NewEntry("cn=testgroup1,ou=Groups,dc=test", map[string][]string{
+		"cn":     {"testgroup1"},
+		"Member": {"cn=testuser1,ou=People,dc=test"},
+	})
+	setAttributeValues(entry, "member", []string{"cn=testuser2,ou=People,dc=test"})
+	expected := []string{"cn=testuser2,ou=People,dc=test"}
+	if val := entry.GetAttributeValues("member"); !reflect.DeepEqual(val, expected) {
+		t.Errorf("Unexpected member values\nExpected: %v\nGot: %v", expected, val)
+	}
+	if len(entry.Attributes) != 2 {
+		t.Errorf("Unexpected number of attributes, got: %d", len(entry.Attributes))
+	}
+	setAttributeValues(entry, "memberUid", []string{"testuser2"})
+	expected = []string{"testuser2"}
+	if val := entry.GetAttributeValues("memberUid"); !reflect.DeepEqual(val, expected) {
+		t.Errorf("Unexpected memberUid values\nExpected: %v\nGot: %v", expected, val)
+	}
+}
+
+func TestMembersInChainFilter(t *testing.T) {
+	filter := membersInChainFilter("cn=test(group),ou=Groups,dc=test", "(objectClass=person)")
+	expected := `(&(objectClass=person)(memberOf:1.2.840.113556.1.4.1941:=cn=test\28group\29,ou=Groups,dc=test))`
+	if filter != expected {
+		t.Errorf("Unexpected filter\nExpected: %s\nGot: %s", expected, filter)
+	}
+}
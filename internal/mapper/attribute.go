@@ -0,0 +1,206 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	"github.com/OSC/k8-ldap-configmap/internal/utils"
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+var (
+	validAttributeEntities  = []string{"user", "group"}
+	validAttributeOutputs   = []string{"single", "json"}
+	attributeValueSeparator = "+"
+)
+
+// ParseAttributeMapper parses an attribute mapper definition such as
+// name=user-shell,entity=user,key=uid,value=loginShell,output=single,configmap=user-shell-map
+func ParseAttributeMapper(definition string) (config.AttributeMapper, error) {
+	attrs := utils.AttrMap(definition)
+	m := config.AttributeMapper{
+		Name:          attrs["name"],
+		Entity:        attrs["entity"],
+		KeyAttr:       attrs["key"],
+		Output:        attrs["output"],
+		ConfigMapName: attrs["configmap"],
+	}
+	if m.Name == "" {
+		return m, fmt.Errorf("attribute mapper %s is missing name", definition)
+	}
+	if _, ok := registry.factories[m.Name]; ok && !utils.SliceContains(registry.attributeMappers, m.Name) {
+		return m, fmt.Errorf("attribute mapper name %s conflicts with an existing mapper", m.Name)
+	}
+	if m.Entity == "" {
+		m.Entity = "user"
+	}
+	if !utils.SliceContains(validAttributeEntities, m.Entity) {
+		return m, fmt.Errorf("attribute mapper %s entity %s is not valid", m.Name, m.Entity)
+	}
+	for _, value := range strings.Split(attrs["value"], attributeValueSeparator) {
+		if value != "" {
+			m.ValueAttrs = append(m.ValueAttrs, value)
+		}
+	}
+	if len(m.ValueAttrs) == 0 {
+		return m, fmt.Errorf("attribute mapper %s is missing value", m.Name)
+	}
+	if m.Output == "" {
+		m.Output = "single"
+	}
+	if !utils.SliceContains(validAttributeOutputs, m.Output) {
+		return m, fmt.Errorf("attribute mapper %s output %s is not valid", m.Name, m.Output)
+	}
+	if m.ConfigMapName == "" {
+		m.ConfigMapName = fmt.Sprintf("%s-map", m.Name)
+	}
+	return m, nil
+}
+
+// RegisterAttributeMappers registers each attribute mapper so it can be used like any other mapper.
+// Attribute mappers from previous calls are removed first.
+func RegisterAttributeMappers(definitions []config.AttributeMapper) {
+	registry.RegisterAttributeMappers(definitions)
+}
+
+// RegisterAttributeMappers registers each attribute mapper in the registry,
+// attribute mappers from previous calls are removed first.
+func (r *Registry) RegisterAttributeMappers(definitions []config.AttributeMapper) {
+	r.unregisterMappers(r.attributeMappers)
+	r.attributeMappers = []string{}
+	for _, definition := range definitions {
+		d := definition
+		var required []string
+		ldapAttrs := append([]string{}, d.ValueAttrs...)
+		if d.KeyAttr == "" {
+			required = []string{"name"}
+		} else {
+			ldapAttrs = append(ldapAttrs, d.KeyAttr)
+		}
+		factory := func(config *config.Config, logger *slog.Logger) Mapper {
+			return NewAttributeMapper(d, config, logger)
+		}
+		if d.Entity == "user" {
+			r.registerMapper(d.Name, required, nil, factory)
+			r.requiredUserLDAPAttrs[d.Name] = ldapAttrs
+		} else {
+			r.registerMapper(d.Name, nil, required, factory)
+			r.requiredGroupLDAPAttrs[d.Name] = ldapAttrs
+		}
+		r.attributeMappers = append(r.attributeMappers, d.Name)
+	}
+}
+
+// RequiredLDAPAttrs returns the LDAP attributes, not defined by attribute maps, that enabled mappers require
+func RequiredLDAPAttrs(attrType string, enabledMappers []string) []string {
+	return registry.RequiredLDAPAttrs(attrType, enabledMappers)
+}
+
+// RequiredLDAPAttrs returns the LDAP attributes, not defined by attribute maps, that enabled mappers require
+func (r *Registry) RequiredLDAPAttrs(attrType string, enabledMappers []string) []string {
+	var requiredByType map[string][]string
+	if attrType == "user" {
+		requiredByType = r.requiredUserLDAPAttrs
+	} else {
+		requiredByType = r.requiredGroupLDAPAttrs
+	}
+	attrs := []string{}
+	for _, mapper := range mapperTypes(enabledMappers) {
+		for _, attr := range requiredByType[mapper] {
+			if !utils.SliceContains(attrs, attr) {
+				attrs = append(attrs, attr)
+			}
+		}
+	}
+	return attrs
+}
+
+func NewAttributeMapper(definition config.AttributeMapper, config *config.Config, logger *slog.Logger) Mapper {
+	return &Attribute{
+		definition: definition,
+		config:     config,
+		logger:     logger,
+	}
+}
+
+type Attribute struct {
+	definition config.AttributeMapper
+	config     *config.Config
+	logger     *slog.Logger
+}
+
+func (m Attribute) Name() string {
+	return instanceName(m.config, m.definition.Name)
+}
+
+func (m Attribute) ConfigMapName() string {
+	return ConfigMapName(m.config, m.Name(), m.definition.ConfigMapName)
+}
+
+func (m Attribute) GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error) {
+	m.logger.Debug("Mapper running")
+	var entries []*ldap.Entry
+	var attrMap map[string]string
+	var prefix string
+	if m.definition.Entity == "user" {
+		entries = users.Entries
+		attrMap = m.config.UserAttrMap
+		prefix = m.config.UserPrefix
+	} else {
+		entries = groups.Entries
+		attrMap = m.config.GroupAttrMap
+	}
+	keyAttr := m.definition.KeyAttr
+	if keyAttr == "" {
+		keyAttr = attrMap["name"]
+	}
+	if keyAttr != attrMap["name"] {
+		prefix = ""
+	}
+	data := make(map[string]string)
+	for _, entry := range entries {
+		key := entry.GetAttributeValue(keyAttr)
+		if key == "" {
+			m.logger.Debug("Skipping entry without key attribute", "dn", entry.DN, "attribute", keyAttr)
+			continue
+		}
+		key = fmt.Sprintf("%s%s", prefix, key)
+		switch m.definition.Output {
+		case "json":
+			values := []string{}
+			for _, attr := range m.definition.ValueAttrs {
+				values = append(values, entry.GetAttributeValues(attr)...)
+			}
+			sort.Strings(values)
+			valuesJSON, _ := json.Marshal(values)
+			data[key] = string(valuesJSON)
+		default:
+			var value string
+			for _, attr := range m.definition.ValueAttrs {
+				if value = entry.GetAttributeValue(attr); value != "" {
+					break
+				}
+			}
+			data[key] = value
+		}
+	}
+	m.logger.Debug("Mapper complete", "keys", len(data))
+	return data, nil
+}
@@ -0,0 +1,170 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	ldapgo "github.com/go-ldap/ldap/v3"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestParseAttributeMapper(t *testing.T) {
+	m, err := ParseAttributeMapper("name=user-shell,value=loginShell")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := config.AttributeMapper{
+		Name:          "user-shell",
+		Entity:        "user",
+		ValueAttrs:    []string{"loginShell"},
+		Output:        "single",
+		ConfigMapName: "user-shell-map",
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("Unexpected attribute mapper\nExpected: %v\nGot: %v", expected, m)
+	}
+	m, err = ParseAttributeMapper("name=group-mail,entity=group,key=gidNumber,value=mail+mailAlternateAddress,output=json,configmap=mail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = config.AttributeMapper{
+		Name:          "group-mail",
+		Entity:        "group",
+		KeyAttr:       "gidNumber",
+		ValueAttrs:    []string{"mail", "mailAlternateAddress"},
+		Output:        "json",
+		ConfigMapName: "mail",
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("Unexpected attribute mapper\nExpected: %v\nGot: %v", expected, m)
+	}
+	invalid := []string{
+		"value=loginShell",
+		"name=user-shell",
+		"name=user-shell,entity=foo,value=loginShell",
+		"name=user-shell,output=foo,value=loginShell",
+		"name=user-uid,value=loginShell",
+	}
+	for _, definition := range invalid {
+		if _, err := ParseAttributeMapper(definition); err == nil {
+			t.Errorf("Expected error parsing %s", definition)
+		}
+	}
+}
+
+func TestRegisterAttributeMappers(t *testing.T) {
+	defer RegisterAttributeMappers(nil)
+	definitions := []config.AttributeMapper{
+		{Name: "user-shell", Entity: "user", ValueAttrs: []string{"loginShell"}, Output: "single", ConfigMapName: "user-shell-map"},
+		{Name: "gid-mail", Entity: "group", KeyAttr: "gidNumber", ValueAttrs: []string{"mail"}, Output: "json", ConfigMapName: "gid-mail-map"},
+	}
+	RegisterAttributeMappers(definitions)
+	enabled := []string{"user-uid", "user-shell", "gid-mail"}
+	if val := RequiredAttrs("user", enabled); !reflect.DeepEqual(val, []string{"name", "uid"}) {
+		t.Errorf("Unexpected required user attrs, got: %v", val)
+	}
+	if val := RequiredAttrs("group", enabled); len(val) != 0 {
+		t.Errorf("Unexpected required group attrs, got: %v", val)
+	}
+	if val := RequiredLDAPAttrs("user", enabled); !reflect.DeepEqual(val, []string{"loginShell"}) {
+		t.Errorf("Unexpected required user LDAP attrs, got: %v", val)
+	}
+	if val := RequiredLDAPAttrs("group", enabled); !reflect.DeepEqual(val, []string{"mail", "gidNumber"}) {
+		t.Errorf("Unexpected required group LDAP attrs, got: %v", val)
+	}
+	mappers := GetMappers(&config.Config{EnabledMappers: enabled}, promslog.NewNopLogger())
+	if len(mappers) != 3 {
+		t.Errorf("Unexpected number of mappers, got: %d", len(mappers))
+	}
+	RegisterAttributeMappers(definitions[:1])
+	if _, ok := registry.factories["gid-mail"]; ok {
+		t.Errorf("Expected gid-mail to be removed")
+	}
+}
+
+func TestGetAttributeMapper(t *testing.T) {
+	users := &ldapgo.SearchResult{
+		Entries: []*ldapgo.Entry{
+			ldapgo.NewEntry("uid=testuser1,ou=people,dc=example,dc=com", map[string][]string{
+				"uid":                  {"testuser1"},
+				"uidNumber":            {"1000"},
+				"mail":                 {"testuser1@example.com"},
+				"mailAlternateAddress": {"user1@example.com"},
+			}),
+			ldapgo.NewEntry("uid=testuser2,ou=people,dc=example,dc=com", map[string][]string{
+				"uid":                  {"testuser2"},
+				"uidNumber":            {"1001"},
+				"mailAlternateAddress": {"user2@example.com"},
+			}),
+			ldapgo.NewEntry("uid=testuser3,ou=people,dc=example,dc=com", map[string][]string{
+				"uid":       {"testuser3"},
+				"uidNumber": {"1002"},
+			}),
+		},
+	}
+	mockConfig := &config.Config{
+		UserPrefix: "user-",
+		UserAttrMap: map[string]string{
+			"name": "uid",
+		},
+	}
+	mapper := NewAttributeMapper(config.AttributeMapper{
+		Name:          "user-mail",
+		Entity:        "user",
+		ValueAttrs:    []string{"mail", "mailAlternateAddress"},
+		Output:        "single",
+		ConfigMapName: "user-mail-map",
+	}, mockConfig, promslog.NewNopLogger())
+	if mapper.Name() != "user-mail" {
+		t.Errorf("Unexpected name, got: %s", mapper.Name())
+	}
+	if mapper.ConfigMapName() != "user-mail-map" {
+		t.Errorf("Unexpected ConfigMap name, got: %s", mapper.ConfigMapName())
+	}
+	data, err := mapper.GetData(users, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"user-testuser1": "testuser1@example.com",
+		"user-testuser2": "user2@example.com",
+		"user-testuser3": "",
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Unexpected data\nExpected: %v\nGot: %v", expected, data)
+	}
+	mapper = NewAttributeMapper(config.AttributeMapper{
+		Name:          "uid-mail",
+		Entity:        "user",
+		KeyAttr:       "uidNumber",
+		ValueAttrs:    []string{"mail", "mailAlternateAddress"},
+		Output:        "json",
+		ConfigMapName: "uid-mail-map",
+	}, mockConfig, promslog.NewNopLogger())
+	data, err = mapper.GetData(users, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string]string{
+		"1000": `["testuser1@example.com","user1@example.com"]`,
+		"1001": `["user2@example.com"]`,
+		"1002": `[]`,
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Unexpected data\nExpected: %v\nGot: %v", expected, data)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	"github.com/OSC/k8-ldap-configmap/internal/utils"
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+// ValidateAutomountMap returns an error if an automount map can not be used as a mapper
+func ValidateAutomountMap(mapName string) error {
+	if mapName == "" {
+		return fmt.Errorf("automount map name must not be empty")
+	}
+	if _, ok := registry.factories[mapName]; ok && !utils.SliceContains(registry.automountMappers, mapName) {
+		return fmt.Errorf("automount map %s conflicts with an existing mapper", mapName)
+	}
+	return nil
+}
+
+// RegisterAutomountMappers registers a mapper for each automount map, the maps in userMaps
+// also have keys for each user resolved from the map.
+// Automount mappers from previous calls are removed first.
+func RegisterAutomountMappers(maps []string, userMaps []string) {
+	registry.RegisterAutomountMappers(maps, userMaps)
+}
+
+// RegisterAutomountMappers registers a mapper for each automount map in the registry,
+// automount mappers from previous calls are removed first.
+func (r *Registry) RegisterAutomountMappers(maps []string, userMaps []string) {
+	r.unregisterMappers(r.automountMappers)
+	r.automountMappers = []string{}
+	for _, mapName := range maps {
+		name := mapName
+		userResolved := utils.SliceContains(userMaps, name)
+		var requiredUser []string
+		if userResolved {
+			requiredUser = []string{"name"}
+		}
+		r.registerMapper(name, requiredUser, nil, func(config *config.Config, logger *slog.Logger) Mapper {
+			return NewAutomountMapper(name, userResolved, config, logger)
+		})
+		r.automountMappers = append(r.automountMappers, name)
+	}
+}
+
+func NewAutomountMapper(mapName string, userResolved bool, config *config.Config, logger *slog.Logger) Mapper {
+	return &Automount{
+		mapName:      mapName,
+		userResolved: userResolved,
+		config:       config,
+		logger:       logger,
+	}
+}
+
+type Automount struct {
+	mapName      string
+	userResolved bool
+	config       *config.Config
+	logger       *slog.Logger
+}
+
+func (m Automount) Name() string {
+	return instanceName(m.config, m.mapName)
+}
+
+func (m Automount) ConfigMapName() string {
+	return ConfigMapName(m.config, m.Name(), fmt.Sprintf("%s-map", m.mapName))
+}
+
+func (m Automount) MapName() string {
+	return m.mapName
+}
+
+func (m Automount) GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error) {
+	return m.GetAutomountData(users, &ldap.SearchResult{})
+}
+
+func (m Automount) GetAutomountData(users *ldap.SearchResult, automounts *ldap.SearchResult) (map[string]string, error) {
+	m.logger.Debug("Mapper running")
+	locations := make(map[string]string)
+	data := make(map[string]string)
+	for _, entry := range automounts.Entries {
+		key := entry.GetAttributeValue("automountKey")
+		location := AutomountLocation(entry.GetAttributeValue("automountInformation"))
+		if key == "" || location == "" {
+			m.logger.Debug("Skipping automount entry without key or location", "dn", entry.DN)
+			continue
+		}
+		locations[key] = location
+		data[key] = location
+	}
+	if m.userResolved && users != nil {
+		for _, entry := range users.Entries {
+			name := entry.GetAttributeValue(m.config.UserAttrMap["name"])
+			location, ok := locations[name]
+			if !ok {
+				location, ok = locations["*"]
+			}
+			if !ok {
+				continue
+			}
+			data[fmt.Sprintf("%s%s", m.config.UserPrefix, name)] = strings.ReplaceAll(location, "&", name)
+		}
+	}
+	m.logger.Debug("Mapper complete", "keys", len(data))
+	return data, nil
+}
+
+// AutomountLocation returns the location, such as server:/path, from automountInformation
+// by removing any mount options.
+func AutomountLocation(information string) string {
+	location := ""
+	for _, field := range strings.Fields(information) {
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+		location = field
+		break
+	}
+	return location
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/OSC/k8-ldap-configmap/internal/ldap"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestAutomountLocation(t *testing.T) {
+	tests := map[string]string{
+		"-rw,hard nfs1:/home/&":     "nfs1:/home/&",
+		"nfs1:/home/user1":          "nfs1:/home/user1",
+		"-fstype=nfs4 -rw nfs1:/p1": "nfs1:/p1",
+		"-rw":                       "",
+		"":                          "",
+	}
+	for information, expected := range tests {
+		if val := AutomountLocation(information); val != expected {
+			t.Errorf("Unexpected location for %s\nExpected: %s\nGot: %s", information, expected, val)
+		}
+	}
+}
+
+func TestRegisterAutomountMappers(t *testing.T) {
+	defer RegisterAutomountMappers(nil, nil)
+	RegisterAutomountMappers([]string{"auto.home", "auto.project"}, []string{"auto.home"})
+	if val := RequiredAttrs("user", []string{"auto.home", "auto.project"}); !reflect.DeepEqual(val, []string{"name"}) {
+		t.Errorf("Unexpected required user attrs, got: %v", val)
+	}
+	if err := ValidateAutomountMap("auto.home"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := ValidateAutomountMap("user-uid"); err == nil {
+		t.Errorf("Expected error for automount map conflicting with mapper")
+	}
+	RegisterAutomountMappers([]string{"auto.home"}, nil)
+	if _, ok := registry.factories["auto.project"]; ok {
+		t.Errorf("Expected auto.project to be removed")
+	}
+}
+
+func TestGetAutomount(t *testing.T) {
+	l, err := ldap.LDAPConnect(_config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := ldap.LDAPUsers(l, _config.UserFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	automounts, err := ldap.LDAPAutomounts(l, "auto.home", _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapper := NewAutomountMapper("auto.home", true, _config, promslog.NewNopLogger())
+	if mapper.ConfigMapName() != "auto.home-map" {
+		t.Errorf("Unexpected ConfigMap name, got: %s", mapper.ConfigMapName())
+	}
+	data, err := mapper.(AutomountMapper).GetAutomountData(users, automounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"*":         "nfs1:/home/&",
+		"testuser1": "nfs1:/home/testuser1",
+		"testuser2": "nfs2:/export/home/testuser2",
+		"testuser3": "nfs1:/home/testuser3",
+		"testuser4": "nfs1:/home/testuser4",
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Unexpected data\nExpected: %v\nGot: %v", expected, data)
+	}
+	mapper = NewAutomountMapper("auto.home", false, _config, promslog.NewNopLogger())
+	data, err = mapper.(AutomountMapper).GetAutomountData(users, automounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string]string{
+		"*":         "nfs1:/home/&",
+		"testuser2": "nfs2:/export/home/testuser2",
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Unexpected data\nExpected: %v\nGot: %v", expected, data)
+	}
+}
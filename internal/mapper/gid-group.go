@@ -0,0 +1,58 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"log/slog"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+func init() {
+	registerMapper("gid-group", nil, []string{"name", "gid"}, NewGIDGroupMapper)
+}
+
+func NewGIDGroupMapper(config *config.Config, logger *slog.Logger) Mapper {
+	return &GIDGroup{
+		config: config,
+		logger: logger,
+	}
+}
+
+type GIDGroup struct {
+	config *config.Config
+	logger *slog.Logger
+}
+
+func (m GIDGroup) Name() string {
+	return instanceName(m.config, "gid-group")
+}
+
+func (m GIDGroup) ConfigMapName() string {
+	return ConfigMapName(m.config, m.Name(), "gid-group-map")
+}
+
+func (m GIDGroup) GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error) {
+	m.logger.Debug("Mapper running")
+	pairs := [][2]string{}
+	for _, entry := range groups.Entries {
+		name := entry.GetAttributeValue(m.config.GroupAttrMap["name"])
+		gid := entry.GetAttributeValue(m.config.GroupAttrMap["gid"])
+		pairs = append(pairs, [2]string{gid, name})
+	}
+	gidGroups := reverseMap(m.Name(), pairs, m.logger)
+	m.logger.Debug("Mapper complete", "gid-groups", len(gidGroups))
+	return gidGroups, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"testing"
+
+	"github.com/OSC/k8-ldap-configmap/internal/ldap"
+	"github.com/OSC/k8-ldap-configmap/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestGetGIDGroups(t *testing.T) {
+	mapper := NewGIDGroupMapper(_config, promslog.NewNopLogger())
+	l, err := ldap.LDAPConnect(_config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := ldap.LDAPGroups(l, _config.GroupFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := mapper.GetData(nil, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 3 {
+		t.Errorf("Unexpected length of data, got: %d", len(data))
+	}
+	if val, ok := data["1000"]; !ok {
+		t.Errorf("1000 not found in data")
+	} else if val != "testgroup2" {
+		t.Errorf("Unexpected value for 1000, got:%s", val)
+	}
+	if val, ok := data["1003"]; !ok {
+		t.Errorf("1003 not found in data")
+	} else if val != "testgroup4" {
+		t.Errorf("Unexpected value for 1003, got:%s", val)
+	}
+	if val := testutil.ToFloat64(metrics.MetricCollisions.WithLabelValues("gid-group")); val != 0 {
+		t.Errorf("Unexpected collisions, got: %v", val)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"log/slog"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+func init() {
+	registerMapper("group-gid", nil, []string{"name", "gid"}, NewGroupGIDMapper)
+}
+
+func NewGroupGIDMapper(config *config.Config, logger *slog.Logger) Mapper {
+	return &GroupGID{
+		config: config,
+		logger: logger,
+	}
+}
+
+type GroupGID struct {
+	config *config.Config
+	logger *slog.Logger
+}
+
+func (m GroupGID) Name() string {
+	return instanceName(m.config, "group-gid")
+}
+
+func (m GroupGID) ConfigMapName() string {
+	return ConfigMapName(m.config, m.Name(), "group-gid-map")
+}
+
+func (m GroupGID) GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error) {
+	m.logger.Debug("Mapper running")
+	groupGIDs := make(map[string]string)
+	for _, entry := range groups.Entries {
+		name := entry.GetAttributeValue(m.config.GroupAttrMap["name"])
+		gid := entry.GetAttributeValue(m.config.GroupAttrMap["gid"])
+		groupGIDs[name] = gid
+	}
+	m.logger.Debug("Mapper complete", "group-gids", len(groupGIDs))
+	return groupGIDs, nil
+}
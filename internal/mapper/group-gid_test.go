@@ -0,0 +1,50 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"testing"
+
+	"github.com/OSC/k8-ldap-configmap/internal/ldap"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestGetGroupGIDs(t *testing.T) {
+	mapper := NewGroupGIDMapper(_config, promslog.NewNopLogger())
+	l, err := ldap.LDAPConnect(_config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := ldap.LDAPGroups(l, _config.GroupFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := mapper.GetData(nil, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 3 {
+		t.Errorf("Unexpected length of data, got: %d", len(data))
+	}
+	if val, ok := data["testgroup1"]; !ok {
+		t.Errorf("testgroup1 not found in data")
+	} else if val != "1001" {
+		t.Errorf("Unexpected value for testgroup1, got:%s", val)
+	}
+	if val, ok := data["testgroup4"]; !ok {
+		t.Errorf("testgroup4 not found in data")
+	} else if val != "1003" {
+		t.Errorf("Unexpected value for testgroup4, got:%s", val)
+	}
+}
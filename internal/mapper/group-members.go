@@ -0,0 +1,67 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+func init() {
+	registerMapper("group-members", []string{"name", "gid"}, []string{"name", "gid"}, NewGroupMembersMapper)
+}
+
+func NewGroupMembersMapper(config *config.Config, logger *slog.Logger) Mapper {
+	return &GroupMembers{
+		config: config,
+		logger: logger,
+	}
+}
+
+type GroupMembers struct {
+	config *config.Config
+	logger *slog.Logger
+}
+
+func (m GroupMembers) Name() string {
+	return instanceName(m.config, "group-members")
+}
+
+func (m GroupMembers) ConfigMapName() string {
+	return ConfigMapName(m.config, m.Name(), "group-members-map")
+}
+
+func (m GroupMembers) GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error) {
+	m.logger.Debug("Mapper running")
+	data, err := GetUserGroups(users, groups, m.Name(), m.config, m.logger)
+	if err != nil {
+		return nil, err
+	}
+	members := GetGroupMembers(data)
+	groupMembers := make(map[string]string)
+	for _, entry := range groups.Entries {
+		name := entry.GetAttributeValue(m.config.GroupAttrMap["name"])
+		groupUsers := []string{}
+		if u, ok := members[name]; ok {
+			groupUsers = u
+		}
+		groupMembersJSON, _ := json.Marshal(groupUsers)
+		groupMembers[name] = string(groupMembersJSON)
+	}
+	m.logger.Debug("Mapper complete", "group-members", len(groupMembers))
+	return groupMembers, nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"testing"
+
+	"github.com/OSC/k8-ldap-configmap/internal/ldap"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestGetGroupMembersMemberOf(t *testing.T) {
+	_config.MemberScheme = "memberof"
+	mapper := NewGroupMembersMapper(_config, promslog.NewNopLogger())
+	l, err := ldap.LDAPConnect(_config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := ldap.LDAPUsers(l, _config.UserFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := ldap.LDAPGroups(l, _config.GroupFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := mapper.GetData(users, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 3 {
+		t.Errorf("Unexpected length of data, got: %d", len(data))
+	}
+	if val, ok := data["testgroup1"]; !ok {
+		t.Errorf("testgroup1 not found in data")
+	} else if val != "[\"testuser1\",\"testuser2\"]" {
+		t.Errorf("Unexpected value for testgroup1, got:%s", val)
+	}
+	if val, ok := data["testgroup2"]; !ok {
+		t.Errorf("testgroup2 not found in data")
+	} else if val != "[\"testuser1\",\"testuser2\",\"testuser3\",\"testuser4\"]" {
+		t.Errorf("Unexpected value for testgroup2, got:%s", val)
+	}
+	if val, ok := data["testgroup4"]; !ok {
+		t.Errorf("testgroup4 not found in data")
+	} else if val != "[]" {
+		t.Errorf("Unexpected value for testgroup4, got:%s", val)
+	}
+}
+
+func TestGetGroupMembersMember(t *testing.T) {
+	_config.MemberScheme = "member"
+	mapper := NewGroupMembersMapper(_config, promslog.NewNopLogger())
+	l, err := ldap.LDAPConnect(_config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := ldap.LDAPUsers(l, _config.UserFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := ldap.LDAPGroups(l, _config.GroupFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := mapper.GetData(users, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 3 {
+		t.Errorf("Unexpected length of data, got: %d", len(data))
+	}
+	if val, ok := data["testgroup1"]; !ok {
+		t.Errorf("testgroup1 not found in data")
+	} else if val != "[\"testuser1\",\"testuser2\"]" {
+		t.Errorf("Unexpected value for testgroup1, got:%s", val)
+	}
+	if val, ok := data["testgroup2"]; !ok {
+		t.Errorf("testgroup2 not found in data")
+	} else if val != "[\"testuser2\",\"testuser3\",\"testuser4\"]" {
+		t.Errorf("Unexpected value for testgroup2, got:%s", val)
+	}
+	if val, ok := data["testgroup4"]; !ok {
+		t.Errorf("testgroup4 not found in data")
+	} else if val != "[\"testuser2\",\"testuser4\"]" {
+		t.Errorf("Unexpected value for testgroup4, got:%s", val)
+	}
+}
+
+func TestGetGroupMembersMemberUID(t *testing.T) {
+	_config.MemberScheme = "memberuid"
+	_config.UserPrefix = "user-"
+	defer func() { _config.UserPrefix = "" }()
+	mapper := NewGroupMembersMapper(_config, promslog.NewNopLogger())
+	l, err := ldap.LDAPConnect(_config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := ldap.LDAPUsers(l, _config.UserFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := ldap.LDAPGroups(l, _config.GroupFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := mapper.GetData(users, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 3 {
+		t.Errorf("Unexpected length of data, got: %d", len(data))
+	}
+	if val, ok := data["testgroup1"]; !ok {
+		t.Errorf("testgroup1 not found in data")
+	} else if val != "[\"user-testuser1\",\"user-testuser2\",\"user-testuser3\"]" {
+		t.Errorf("Unexpected value for testgroup1, got:%s", val)
+	}
+	if val, ok := data["testgroup2"]; !ok {
+		t.Errorf("testgroup2 not found in data")
+	} else if val != "[\"user-testuser2\",\"user-testuser3\",\"user-testuser4\"]" {
+		t.Errorf("Unexpected value for testgroup2, got:%s", val)
+	}
+	if val, ok := data["testgroup4"]; !ok {
+		t.Errorf("testgroup4 not found in data")
+	} else if val != "[\"user-testuser2\",\"user-testuser4\"]" {
+		t.Errorf("Unexpected value for testgroup4, got:%s", val)
+	}
+}
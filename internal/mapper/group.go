@@ -0,0 +1,79 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+func init() {
+	registerMapper("group", []string{"name", "gid"}, []string{"name", "gid"}, NewGroupFileMapper)
+}
+
+func NewGroupFileMapper(config *config.Config, logger *slog.Logger) Mapper {
+	return &GroupFile{
+		config: config,
+		logger: logger,
+	}
+}
+
+type GroupFile struct {
+	config *config.Config
+	logger *slog.Logger
+}
+
+func (m GroupFile) Name() string {
+	return instanceName(m.config, "group")
+}
+
+func (m GroupFile) ConfigMapName() string {
+	return ConfigMapName(m.config, m.Name(), "group-map")
+}
+
+func (m GroupFile) GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error) {
+	m.logger.Debug("Mapper running")
+	data, err := GetUserGroups(users, groups, m.Name(), m.config, m.logger)
+	if err != nil {
+		return nil, err
+	}
+	members := GetGroupMembers(data)
+	lines := []string{}
+	for _, entry := range groups.Entries {
+		name := entry.GetAttributeValue(m.config.GroupAttrMap["name"])
+		if name == "" {
+			m.logger.Debug("Skipping group without name", "dn", entry.DN)
+			continue
+		}
+		groupUsers := []string{}
+		for _, member := range members[name] {
+			// The passwd file does not use the user prefix so group members must not either
+			groupUsers = append(groupUsers, nssFieldReplacer.Replace(strings.TrimPrefix(member, m.config.UserPrefix)))
+		}
+		fields := []string{
+			nssFieldReplacer.Replace(name),
+			"x",
+			nssFieldReplacer.Replace(entry.GetAttributeValue(m.config.GroupAttrMap["gid"])),
+			strings.Join(groupUsers, ","),
+		}
+		lines = append(lines, strings.Join(fields, ":"))
+	}
+	sort.Strings(lines)
+	m.logger.Debug("Mapper complete", "group", len(lines))
+	return map[string]string{"group": nssFile(lines)}, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"testing"
+
+	"github.com/OSC/k8-ldap-configmap/internal/ldap"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestGetGroupFileMemberUID(t *testing.T) {
+	_config.MemberScheme = "memberuid"
+	_config.UserPrefix = "user-"
+	defer func() { _config.UserPrefix = "" }()
+	mapper := NewGroupFileMapper(_config, promslog.NewNopLogger())
+	l, err := ldap.LDAPConnect(_config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := ldap.LDAPUsers(l, _config.UserFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := ldap.LDAPGroups(l, _config.GroupFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := mapper.GetData(users, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "testgroup1:x:1001:testuser1,testuser2,testuser3\n" +
+		"testgroup2:x:1000:testuser2,testuser3,testuser4\n" +
+		"testgroup4:x:1003:testuser2,testuser4\n"
+	if len(data) != 1 {
+		t.Errorf("Unexpected length of data, got: %d", len(data))
+	}
+	if val, ok := data["group"]; !ok {
+		t.Errorf("group not found in data")
+	} else if val != expected {
+		t.Errorf("Unexpected value for group\nExpected: %q\nGot: %q", expected, val)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+var (
+	// LDAPUserRequiredAttrs are the user attributes required by LDAPUser custom resources
+	LDAPUserRequiredAttrs = []string{"name", "uid", "gid"}
+	// LDAPUserOptionalAttrs are the user attributes used by LDAPUser custom resources when defined
+	LDAPUserOptionalAttrs = []string{"home"}
+	// LDAPGroupRequiredAttrs are the group attributes required by LDAPGroup custom resources
+	LDAPGroupRequiredAttrs = []string{"name", "gid"}
+)
+
+// LDAPUserSpec is the spec of an LDAPUser custom resource
+type LDAPUserSpec struct {
+	Username string   `json:"username"`
+	UID      int64    `json:"uid"`
+	GID      int64    `json:"gid"`
+	Home     string   `json:"home,omitempty"`
+	Groups   []string `json:"groups"`
+	GIDs     []int64  `json:"gids"`
+}
+
+// LDAPGroupSpec is the spec of an LDAPGroup custom resource
+type LDAPGroupSpec struct {
+	Name    string   `json:"name"`
+	GID     int64    `json:"gid"`
+	Members []string `json:"members"`
+}
+
+// GetLDAPResources returns the spec of each LDAPUser and LDAPGroup keyed by the user or group name
+// without the user prefix, the username and members in the specs have the user prefix applied
+func GetLDAPResources(users *ldap.SearchResult, groups *ldap.SearchResult, config *config.Config, logger *slog.Logger) (map[string]LDAPUserSpec, map[string]LDAPGroupSpec, error) {
+	userGroups, err := GetUserGroups(users, groups, "custom-resources", config, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	userSpecs := make(map[string]LDAPUserSpec)
+	for _, entry := range users.Entries {
+		name := entry.GetAttributeValue(config.UserAttrMap["name"])
+		username := fmt.Sprintf("%s%s", config.UserPrefix, name)
+		uid, err := strconv.ParseInt(entry.GetAttributeValue(config.UserAttrMap["uid"]), 10, 64)
+		if err != nil {
+			logger.Error("Unable to parse UID to int", "err", err, "user", name)
+			return nil, nil, err
+		}
+		gid, err := strconv.ParseInt(entry.GetAttributeValue(config.UserAttrMap["gid"]), 10, 64)
+		if err != nil {
+			logger.Error("Unable to parse GID to int", "err", err, "user", name)
+			return nil, nil, err
+		}
+		spec := LDAPUserSpec{
+			Username: username,
+			UID:      uid,
+			GID:      gid,
+			Groups:   []string{},
+			GIDs:     []int64{},
+		}
+		if attr, ok := config.UserAttrMap["home"]; ok {
+			spec.Home = entry.GetAttributeValue(attr)
+		}
+		for _, group := range userGroups[username] {
+			spec.Groups = append(spec.Groups, group.name)
+			spec.GIDs = append(spec.GIDs, int64(group.gid))
+		}
+		sort.Strings(spec.Groups)
+		sort.Slice(spec.GIDs, func(i, j int) bool { return spec.GIDs[i] < spec.GIDs[j] })
+		userSpecs[name] = spec
+	}
+	groupMembers := GetGroupMembers(userGroups)
+	groupSpecs := make(map[string]LDAPGroupSpec)
+	for _, entry := range groups.Entries {
+		name := entry.GetAttributeValue(config.GroupAttrMap["name"])
+		gid, err := strconv.ParseInt(entry.GetAttributeValue(config.GroupAttrMap["gid"]), 10, 64)
+		if err != nil {
+			logger.Error("Unable to parse GID to int", "err", err, "group", name)
+			return nil, nil, err
+		}
+		members := groupMembers[name]
+		if members == nil {
+			members = []string{}
+		}
+		groupSpecs[name] = LDAPGroupSpec{
+			Name:    name,
+			GID:     gid,
+			Members: members,
+		}
+	}
+	return userSpecs, groupSpecs, nil
+}
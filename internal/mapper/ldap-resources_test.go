@@ -0,0 +1,67 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/OSC/k8-ldap-configmap/internal/ldap"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestGetLDAPResources(t *testing.T) {
+	_config.MemberScheme = "memberof"
+	l, err := ldap.LDAPConnect(_config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := ldap.LDAPUsers(l, _config.UserFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := ldap.LDAPGroups(l, _config.GroupFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	userSpecs, groupSpecs, err := GetLDAPResources(users, groups, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(userSpecs) != 4 {
+		t.Errorf("Unexpected number of users, got: %d", len(userSpecs))
+	}
+	expectedUser := LDAPUserSpec{
+		Username: "testuser1",
+		UID:      1000,
+		GID:      1001,
+		Groups:   []string{"testgroup1", "testgroup2"},
+		GIDs:     []int64{1000, 1001},
+	}
+	if val, ok := userSpecs["testuser1"]; !ok {
+		t.Errorf("testuser1 not found in users")
+	} else if !reflect.DeepEqual(val, expectedUser) {
+		t.Errorf("Unexpected value for testuser1\nExpected: %v\nGot: %v", expectedUser, val)
+	}
+	expectedGroup := LDAPGroupSpec{
+		Name:    "testgroup2",
+		GID:     1000,
+		Members: []string{"testuser1", "testuser2", "testuser3", "testuser4"},
+	}
+	if val, ok := groupSpecs["testgroup2"]; !ok {
+		t.Errorf("testgroup2 not found in groups")
+	} else if !reflect.DeepEqual(val, expectedGroup) {
+		t.Errorf("Unexpected value for testgroup2\nExpected: %v\nGot: %v", expectedGroup, val)
+	}
+}
@@ -16,6 +16,7 @@ package mapper
 import (
 	"fmt"
 	"log/slog"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -26,59 +27,294 @@ import (
 )
 
 var (
-	mapperFactories    = make(map[string]func(config *config.Config, logger *slog.Logger) Mapper)
-	requiredUserAttrs  = make(map[string][]string)
-	requiredGroupAttrs = make(map[string][]string)
+	registry = newRegistry()
 )
 
+// Registry holds the mappers that can be enabled and the attributes they use
+type Registry struct {
+	factories              map[string]func(config *config.Config, logger *slog.Logger) Mapper
+	requiredUserAttrs      map[string][]string
+	requiredGroupAttrs     map[string][]string
+	optionalUserAttrs      map[string][]string
+	optionalGroupAttrs     map[string][]string
+	requiredUserLDAPAttrs  map[string][]string
+	requiredGroupLDAPAttrs map[string][]string
+	attributeMappers       []string
+	templateMappers        []string
+	automountMappers       []string
+}
+
+func newRegistry() *Registry {
+	return &Registry{
+		factories:              make(map[string]func(config *config.Config, logger *slog.Logger) Mapper),
+		requiredUserAttrs:      make(map[string][]string),
+		requiredGroupAttrs:     make(map[string][]string),
+		optionalUserAttrs:      make(map[string][]string),
+		optionalGroupAttrs:     make(map[string][]string),
+		requiredUserLDAPAttrs:  make(map[string][]string),
+		requiredGroupLDAPAttrs: make(map[string][]string),
+		attributeMappers:       []string{},
+		templateMappers:        []string{},
+		automountMappers:       []string{},
+	}
+}
+
+// DefaultRegistry returns the registry used by GetMappers and the package level functions
+func DefaultRegistry() *Registry {
+	return registry
+}
+
+// Clone returns a copy of the registry so mappers can be registered without changing the original
+func (r *Registry) Clone() *Registry {
+	clone := newRegistry()
+	for name, factory := range r.factories {
+		clone.factories[name] = factory
+	}
+	for _, pair := range []struct{ from, to map[string][]string }{
+		{r.requiredUserAttrs, clone.requiredUserAttrs},
+		{r.requiredGroupAttrs, clone.requiredGroupAttrs},
+		{r.optionalUserAttrs, clone.optionalUserAttrs},
+		{r.optionalGroupAttrs, clone.optionalGroupAttrs},
+		{r.requiredUserLDAPAttrs, clone.requiredUserLDAPAttrs},
+		{r.requiredGroupLDAPAttrs, clone.requiredGroupLDAPAttrs},
+	} {
+		for name, attrs := range pair.from {
+			pair.to[name] = attrs
+		}
+	}
+	clone.attributeMappers = append(clone.attributeMappers, r.attributeMappers...)
+	clone.templateMappers = append(clone.templateMappers, r.templateMappers...)
+	clone.automountMappers = append(clone.automountMappers, r.automountMappers...)
+	return clone
+}
+
 type Mapper interface {
 	Name() string
 	ConfigMapName() string
 	GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error)
 }
 
+// SudoRolesMapper is implemented by mappers that also use the sudoRole search results
+type SudoRolesMapper interface {
+	Mapper
+	GetSudoData(users *ldap.SearchResult, groups *ldap.SearchResult, sudoRoles *ldap.SearchResult) (map[string]string, error)
+}
+
+// AutomountMapper is implemented by mappers that use the entries of an automount map
+type AutomountMapper interface {
+	Mapper
+	MapName() string
+	GetAutomountData(users *ldap.SearchResult, automounts *ldap.SearchResult) (map[string]string, error)
+}
+
 type Group struct {
 	name string
 	gid  int
 }
 
+// Name returns the group name
+func (g Group) Name() string {
+	return g.name
+}
+
+// GID returns the group GID
+func (g Group) GID() int {
+	return g.gid
+}
+
 func registerMapper(name string, requiredUser []string, requiredGroup []string, factory func(config *config.Config, logger *slog.Logger) Mapper) {
-	mapperFactories[name] = factory
-	requiredUserAttrs[name] = requiredUser
-	requiredGroupAttrs[name] = requiredGroup
+	registry.registerMapper(name, requiredUser, requiredGroup, factory)
+}
+
+func (r *Registry) registerMapper(name string, requiredUser []string, requiredGroup []string, factory func(config *config.Config, logger *slog.Logger) Mapper) {
+	r.factories[name] = factory
+	r.requiredUserAttrs[name] = requiredUser
+	r.requiredGroupAttrs[name] = requiredGroup
+}
+
+// unregisterMappers removes the mappers from the registry
+func (r *Registry) unregisterMappers(names []string) {
+	for _, name := range names {
+		delete(r.factories, name)
+		delete(r.requiredUserAttrs, name)
+		delete(r.requiredGroupAttrs, name)
+		delete(r.optionalUserAttrs, name)
+		delete(r.optionalGroupAttrs, name)
+		delete(r.requiredUserLDAPAttrs, name)
+		delete(r.requiredGroupLDAPAttrs, name)
+	}
+}
+
+// registerOptionalAttrs records attributes a mapper will use when they are defined
+// in the attribute maps but that are not required to be present.
+// The attribute "*" means every attribute defined in the attribute map.
+func registerOptionalAttrs(name string, optionalUser []string, optionalGroup []string) {
+	registry.registerOptionalAttrs(name, optionalUser, optionalGroup)
+}
+
+func (r *Registry) registerOptionalAttrs(name string, optionalUser []string, optionalGroup []string) {
+	r.optionalUserAttrs[name] = optionalUser
+	r.optionalGroupAttrs[name] = optionalGroup
 }
 
 func GetMappers(config *config.Config, logger *slog.Logger) []Mapper {
 	mappers := []Mapper{}
-	for name, factory := range mapperFactories {
-		if utils.SliceContains(config.EnabledMappers, name) {
-			mapper := factory(config, logger.With("mapper", name))
-			mappers = append(mappers, mapper)
-			metrics.MetricErrorsTotal.WithLabelValues(mapper.Name())
-			metrics.MetricConfigMapSize.WithLabelValues(mapper.ConfigMapName())
-			metrics.MetricConfigMapKeys.WithLabelValues(mapper.ConfigMapName())
+	for _, definition := range config.EnabledMappers {
+		name, mapperType := ParseMapperInstance(definition)
+		factory, ok := registry.factories[mapperType]
+		if !ok {
+			continue
+		}
+		mapper := factory(instanceConfig(config, name, mapperType), logger.With("mapper", name))
+		mappers = append(mappers, mapper)
+		metrics.MetricErrorsTotal.WithLabelValues(mapper.Name())
+		for _, namespace := range config.Namespaces {
+			metrics.MetricConfigMapSize.WithLabelValues(mapper.ConfigMapName(), namespace)
+			metrics.MetricConfigMapKeys.WithLabelValues(mapper.ConfigMapName(), namespace)
 		}
 	}
 	return mappers
 }
 
+// ParseMapperInstance splits a mapper definition of either <type> or <name>:<type>
+// into the instance name and the mapper type, the name of an unnamed mapper is its type
+func ParseMapperInstance(definition string) (string, string) {
+	if name, mapperType, ok := strings.Cut(definition, ":"); ok {
+		return name, mapperType
+	}
+	return definition, definition
+}
+
+// MapperNames returns the instance names of the mapper definitions
+func MapperNames(definitions []string) []string {
+	names := []string{}
+	for _, definition := range definitions {
+		name, _ := ParseMapperInstance(definition)
+		names = append(names, name)
+	}
+	return names
+}
+
+// MapperType returns the type of the enabled mapper with the instance name
+func MapperType(enabledMappers []string, name string) string {
+	for _, definition := range enabledMappers {
+		if instance, mapperType := ParseMapperInstance(definition); instance == name {
+			return mapperType
+		}
+	}
+	return name
+}
+
+func mapperTypes(definitions []string) []string {
+	types := []string{}
+	for _, definition := range definitions {
+		_, mapperType := ParseMapperInstance(definition)
+		types = append(types, mapperType)
+	}
+	return types
+}
+
+// instanceConfig returns the config of a mapper with the mapper specific attribute maps
+// and user prefix applied, named instances get their own copy of the config
+func instanceConfig(config *config.Config, name string, mapperType string) *config.Config {
+	userAttrMap, hasUserAttrMap := config.MappersUserAttrMap[name]
+	groupAttrMap, hasGroupAttrMap := config.MappersGroupAttrMap[name]
+	userPrefix, hasUserPrefix := config.MappersUserPrefix[name]
+	if name == mapperType && !hasUserAttrMap && !hasGroupAttrMap && !hasUserPrefix {
+		return config
+	}
+	instance := *config
+	if name != mapperType {
+		instance.InstanceName = name
+	}
+	if hasUserAttrMap {
+		instance.UserAttrMap = mergeAttrMap(config.UserAttrMap, userAttrMap)
+	}
+	if hasGroupAttrMap {
+		instance.GroupAttrMap = mergeAttrMap(config.GroupAttrMap, groupAttrMap)
+	}
+	if hasUserPrefix {
+		instance.UserPrefix = userPrefix
+	}
+	return &instance
+}
+
+func mergeAttrMap(base map[string]string, overrides map[string]string) map[string]string {
+	attrMap := make(map[string]string)
+	for key, value := range base {
+		attrMap[key] = value
+	}
+	for key, value := range overrides {
+		attrMap[key] = value
+	}
+	return attrMap
+}
+
+// instanceName returns the name of a named mapper instance or the name of the mapper
+func instanceName(config *config.Config, name string) string {
+	if config.InstanceName != "" {
+		return config.InstanceName
+	}
+	return name
+}
+
+// ConfigMapName returns the name of a mapper's ConfigMap, the name can be overridden
+// for each mapper and has the global prefix and suffix applied
+func ConfigMapName(config *config.Config, mapperName string, defaultName string) string {
+	name := defaultName
+	if config.InstanceName != "" {
+		name = fmt.Sprintf("%s-map", config.InstanceName)
+	}
+	if configMapName, ok := config.ConfigMapNames[mapperName]; ok {
+		name = configMapName
+	}
+	return fmt.Sprintf("%s%s%s", config.ConfigMapPrefix, name, config.ConfigMapSuffix)
+}
+
+// ConfigMapNames returns the ConfigMap name of each enabled mapper
+func ConfigMapNames(config *config.Config, logger *slog.Logger) map[string]string {
+	return registry.ConfigMapNames(config, logger)
+}
+
+// ConfigMapNames returns the ConfigMap name of each enabled mapper
+func (r *Registry) ConfigMapNames(config *config.Config, logger *slog.Logger) map[string]string {
+	names := make(map[string]string)
+	for _, definition := range config.EnabledMappers {
+		name, mapperType := ParseMapperInstance(definition)
+		if factory, ok := r.factories[mapperType]; ok {
+			names[name] = factory(instanceConfig(config, name, mapperType), logger).ConfigMapName()
+		}
+	}
+	return names
+}
+
 func ValidMappers() []string {
+	return registry.ValidMappers()
+}
+
+// ValidMappers returns the names of all registered mappers
+func (r *Registry) ValidMappers() []string {
 	validMappers := []string{}
-	for key := range mapperFactories {
+	for key := range r.factories {
 		validMappers = append(validMappers, key)
 	}
 	return validMappers
 }
 
 func RequiredAttrs(attrType string, enabledMappers []string) []string {
+	return registry.RequiredAttrs(attrType, enabledMappers)
+}
+
+// RequiredAttrs returns the attribute map keys of the attribute type that enabled mappers require
+func (r *Registry) RequiredAttrs(attrType string, enabledMappers []string) []string {
 	var requiredByType map[string][]string
 	if attrType == "user" {
-		requiredByType = requiredUserAttrs
+		requiredByType = r.requiredUserAttrs
 	} else {
-		requiredByType = requiredGroupAttrs
+		requiredByType = r.requiredGroupAttrs
 	}
 	attrs := []string{}
-	for _, mapper := range enabledMappers {
+	for _, mapper := range mapperTypes(enabledMappers) {
 		required := requiredByType[mapper]
 		for _, attr := range required {
 			if !utils.SliceContains(attrs, attr) {
@@ -89,6 +325,37 @@ func RequiredAttrs(attrType string, enabledMappers []string) []string {
 	return attrs
 }
 
+func OptionalAttrs(attrType string, enabledMappers []string, attrMap map[string]string) []string {
+	return registry.OptionalAttrs(attrType, enabledMappers, attrMap)
+}
+
+// OptionalAttrs returns the attribute map keys of the attribute type that enabled mappers use when defined
+func (r *Registry) OptionalAttrs(attrType string, enabledMappers []string, attrMap map[string]string) []string {
+	var optionalByType map[string][]string
+	if attrType == "user" {
+		optionalByType = r.optionalUserAttrs
+	} else {
+		optionalByType = r.optionalGroupAttrs
+	}
+	attrs := []string{}
+	for _, mapper := range mapperTypes(enabledMappers) {
+		optional := optionalByType[mapper]
+		if utils.SliceContains(optional, "*") {
+			optional = utils.MapKeysStrings(attrMap)
+			sort.Strings(optional)
+		}
+		for _, attr := range optional {
+			if _, ok := attrMap[attr]; !ok {
+				continue
+			}
+			if !utils.SliceContains(attrs, attr) {
+				attrs = append(attrs, attr)
+			}
+		}
+	}
+	return attrs
+}
+
 func ParseDN(dn string) string {
 	elements := strings.Split(dn, ",")
 	nameElement := elements[0]
@@ -99,7 +366,7 @@ func ParseDN(dn string) string {
 	return name[1]
 }
 
-func GetUserGroups(users *ldap.SearchResult, groups *ldap.SearchResult, config *config.Config, logger *slog.Logger) (map[string][]Group, error) {
+func GetUserGroups(users *ldap.SearchResult, groups *ldap.SearchResult, mapperName string, config *config.Config, logger *slog.Logger) (map[string][]Group, error) {
 	userDNs := make(map[string]string)
 	groupDNs := make(map[string]string)
 	groupToGid := make(map[string]string)
@@ -112,16 +379,42 @@ func GetUserGroups(users *ldap.SearchResult, groups *ldap.SearchResult, config *
 		userDNs[strings.ToLower(entry.DN)] = name
 	}
 
+	memberScheme := config.MemberScheme
+	if config.NestedGroups && config.NestedGroupsInChain {
+		// The in chain matching rule has already expanded group members during the group search
+		memberScheme = "member"
+	}
+	nested := config.NestedGroups && !config.NestedGroupsInChain
+	groupMembers := make(map[string][]string)
+	groupParents := make(map[string][]string)
+
 	for _, entry := range groups.Entries {
 		name := entry.GetAttributeValue(config.GroupAttrMap["name"])
 		gid := entry.GetAttributeValue(config.GroupAttrMap["gid"])
-		groupDNs[strings.ToLower(entry.DN)] = name
+		dn := strings.ToLower(entry.DN)
+		groupDNs[dn] = name
 		groupToGid[name] = gid
 		gidToGroup[gid] = name
+		groupMembers[dn] = entry.GetAttributeValues("member")
+		groupParents[dn] = entry.GetAttributeValues("memberOf")
+	}
+	switch {
+	case nested && memberScheme == "member":
+		nestedGroupCycles(groupMembers, groupDNs, mapperName, logger)
+	case nested && memberScheme == "memberof":
+		nestedGroupCycles(groupParents, groupDNs, mapperName, logger)
+	}
+
+	for _, entry := range groups.Entries {
+		name := entry.GetAttributeValue(config.GroupAttrMap["name"])
 		members := []string{}
-		switch config.MemberScheme {
+		switch memberScheme {
 		case "member":
-			members = GetGroupsMember(entry.GetAttributeValues("member"), userDNs)
+			memberDNs := entry.GetAttributeValues("member")
+			if nested {
+				memberDNs = GetNestedGroupMembers(entry.DN, groupMembers, config.NestedGroupsDepth)
+			}
+			members = GetGroupsMember(memberDNs, userDNs)
 		case "memberuid":
 			members = entry.GetAttributeValues("memberUid")
 		}
@@ -148,8 +441,12 @@ func GetUserGroups(users *ldap.SearchResult, groups *ldap.SearchResult, config *
 		if g, ok := userGroups[key]; ok {
 			groups = g
 		}
-		if config.MemberScheme == "memberof" {
-			groups = GetGroupsMemberOf(entry.GetAttributeValues("memberOf"), groupDNs)
+		if memberScheme == "memberof" {
+			memberOf := entry.GetAttributeValues("memberOf")
+			if nested {
+				memberOf = GetNestedGroupsMemberOf(memberOf, groupParents, config.NestedGroupsDepth)
+			}
+			groups = GetGroupsMemberOf(memberOf, groupDNs)
 		}
 		if !utils.SliceContains(groups, primaryGroup) && primaryGroup != "" {
 			groups = append([]string{primaryGroup}, groups...)
@@ -176,6 +473,49 @@ func GetUserGroups(users *ldap.SearchResult, groups *ldap.SearchResult, config *
 	return data, nil
 }
 
+// GetGroupMembers inverts the data returned by GetUserGroups so that each group name
+// maps to the sorted list of users that are members of that group.
+func GetGroupMembers(userGroups map[string][]Group) map[string][]string {
+	groupMembers := make(map[string][]string)
+	for user, groups := range userGroups {
+		for _, group := range groups {
+			groupMembers[group.name] = append(groupMembers[group.name], user)
+		}
+	}
+	for _, members := range groupMembers {
+		sort.Strings(members)
+	}
+	return groupMembers
+}
+
+// reverseMap builds a map from the first element of each pair to the second.
+// When several names share the same number the collision is logged and counted and
+// the lowest sorting name is kept so the result does not depend on LDAP ordering.
+func reverseMap(mapperName string, pairs [][2]string, logger *slog.Logger) map[string]string {
+	data := make(map[string]string)
+	collisions := 0
+	for _, pair := range pairs {
+		key, value := pair[0], pair[1]
+		if key == "" {
+			continue
+		}
+		existing, ok := data[key]
+		if !ok {
+			data[key] = value
+			continue
+		}
+		collisions++
+		kept, dropped := existing, value
+		if value < existing {
+			kept, dropped = value, existing
+		}
+		logger.Warn("Duplicate key found", "key", key, "kept", kept, "dropped", dropped)
+		data[key] = kept
+	}
+	metrics.MetricCollisions.WithLabelValues(mapperName).Set(float64(collisions))
+	return data
+}
+
 func GetGroupsMemberOf(memberOf []string, groupDNs map[string]string) []string {
 	groups := []string{}
 	for _, m := range memberOf {
@@ -195,3 +535,115 @@ func GetGroupsMember(members []string, userDNs map[string]string) []string {
 	}
 	return users
 }
+
+// findCycles returns the names in each cycle found by following the children of every name
+func findCycles(names []string, children map[string][]string) [][]string {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int)
+	path := []string{}
+	cycles := [][]string{}
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		path = append(path, name)
+		for _, child := range children[name] {
+			switch state[child] {
+			case visiting:
+				for i := len(path) - 1; i >= 0; i-- {
+					if path[i] == child {
+						cycles = append(cycles, append([]string{}, path[i:]...))
+						break
+					}
+				}
+			case 0:
+				visit(child)
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+	}
+	for _, name := range names {
+		if state[name] == 0 {
+			visit(name)
+		}
+	}
+	return cycles
+}
+
+// nestedGroupCycles logs and counts the cycles of groups found by following the nested groups of each group,
+// children maps each lower case group DN to the DNs of its member or parent groups
+func nestedGroupCycles(children map[string][]string, groupDNs map[string]string, mapperName string, logger *slog.Logger) {
+	dns := utils.MapKeysStrings(groupDNs)
+	sort.Strings(dns)
+	nestedGroups := make(map[string][]string)
+	for _, dn := range dns {
+		for _, child := range children[dn] {
+			c := strings.ToLower(child)
+			if _, ok := groupDNs[c]; ok {
+				nestedGroups[dn] = append(nestedGroups[dn], c)
+			}
+		}
+	}
+	cycles := findCycles(dns, nestedGroups)
+	for _, cycle := range cycles {
+		names := []string{}
+		for _, dn := range append(cycle, cycle[0]) {
+			names = append(names, groupDNs[dn])
+		}
+		logger.Warn("Nested group cycle found", "groups", strings.Join(names, " -> "))
+	}
+	metrics.MetricGroupCycles.WithLabelValues(mapperName).Set(float64(len(cycles)))
+}
+
+// GetNestedGroupMembers returns the member DNs of a group including the members of any
+// nested groups up to depth levels of nesting. Group DNs are identified using the keys of
+// groupMembers and each group is only expanded once so membership cycles terminate.
+func GetNestedGroupMembers(dn string, groupMembers map[string][]string, depth int) []string {
+	members := []string{}
+	seen := map[string]bool{strings.ToLower(dn): true}
+	queue := []string{strings.ToLower(dn)}
+	for level := 0; level <= depth && len(queue) > 0; level++ {
+		next := []string{}
+		for _, group := range queue {
+			for _, member := range groupMembers[group] {
+				m := strings.ToLower(member)
+				if seen[m] {
+					continue
+				}
+				seen[m] = true
+				if _, ok := groupMembers[m]; ok {
+					next = append(next, m)
+					continue
+				}
+				members = append(members, member)
+			}
+		}
+		queue = next
+	}
+	return members
+}
+
+// GetNestedGroupsMemberOf returns the memberOf DNs of a user along with the parents of those
+// groups, as defined by groupParents, up to depth levels of nesting.
+func GetNestedGroupsMemberOf(memberOf []string, groupParents map[string][]string, depth int) []string {
+	groups := []string{}
+	seen := make(map[string]bool)
+	queue := memberOf
+	for level := 0; level <= depth && len(queue) > 0; level++ {
+		next := []string{}
+		for _, group := range queue {
+			g := strings.ToLower(group)
+			if seen[g] {
+				continue
+			}
+			seen[g] = true
+			groups = append(groups, group)
+			next = append(next, groupParents[g]...)
+		}
+		queue = next
+	}
+	return groups
+}
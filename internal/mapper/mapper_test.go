@@ -22,7 +22,11 @@ import (
 	"time"
 
 	"github.com/OSC/k8-ldap-configmap/internal/config"
+	"github.com/OSC/k8-ldap-configmap/internal/metrics"
 	"github.com/OSC/k8-ldap-configmap/internal/test"
+	ldapgo "github.com/go-ldap/ldap/v3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/promslog"
 )
 
 const (
@@ -31,12 +35,17 @@ const (
 
 var (
 	_config = &config.Config{
-		LdapURL:     fmt.Sprintf("ldap://%s", ldapserver),
-		GroupBaseDN: test.GroupBaseDN,
-		UserBaseDN:  test.UserBaseDN,
-		BindDN:      test.BindDN,
-		GroupFilter: test.GroupFilterStatus,
-		UserFilter:  test.UserFilter,
+		LdapURL:         fmt.Sprintf("ldap://%s", ldapserver),
+		GroupBaseDN:     test.GroupBaseDN,
+		UserBaseDN:      test.UserBaseDN,
+		BindDN:          test.BindDN,
+		GroupFilter:     test.GroupFilterStatus,
+		UserFilter:      test.UserFilter,
+		NetgroupBaseDN:  test.NetgroupBaseDN,
+		NetgroupFilter:  test.NetgroupFilter,
+		SudoersBaseDN:   test.SudoersBaseDN,
+		SudoersFilter:   test.SudoersFilter,
+		AutomountBaseDN: test.AutomountBaseDN,
 		GroupAttrMap: map[string]string{
 			"name": "cn",
 			"gid":  "gidNumber",
@@ -65,32 +74,32 @@ func TestMain(m *testing.M) {
 }
 
 func TestInitRequiredUserAttrs(t *testing.T) {
-	if val, ok := requiredUserAttrs["user-gid"]; !ok {
+	if val, ok := registry.requiredUserAttrs["user-gid"]; !ok {
 		t.Errorf("user user-gid key missing")
 	} else if !reflect.DeepEqual(val, []string{"name", "gid"}) {
 		t.Errorf("unexpected required attrs for user user-gid, got %v", val)
 	}
-	if val, ok := requiredUserAttrs["user-uid"]; !ok {
+	if val, ok := registry.requiredUserAttrs["user-uid"]; !ok {
 		t.Errorf("user user-uid key missing")
 	} else if !reflect.DeepEqual(val, []string{"name", "uid"}) {
 		t.Errorf("unexpected required attrs for user user-uid, got %v", val)
 	}
-	if val, ok := requiredUserAttrs["user-home"]; !ok {
+	if val, ok := registry.requiredUserAttrs["user-home"]; !ok {
 		t.Errorf("user user-home key missing")
 	} else if !reflect.DeepEqual(val, []string{"name", "home"}) {
 		t.Errorf("unexpected required attrs for user user-home, got %v", val)
 	}
-	if val, ok := requiredUserAttrs["user-groups"]; !ok {
+	if val, ok := registry.requiredUserAttrs["user-groups"]; !ok {
 		t.Errorf("user user-groups key missing")
 	} else if !reflect.DeepEqual(val, []string{"name", "gid"}) {
 		t.Errorf("unexpected required attrs for user user-groups, got %v", val)
 	}
-	if val, ok := requiredUserAttrs["user-all-groups"]; !ok {
+	if val, ok := registry.requiredUserAttrs["user-all-groups"]; !ok {
 		t.Errorf("user user-all-groups key missing")
 	} else if !reflect.DeepEqual(val, []string{"name", "gid"}) {
 		t.Errorf("unexpected required attrs for user user-all-groups, got %v", val)
 	}
-	if val, ok := requiredUserAttrs["user-gids"]; !ok {
+	if val, ok := registry.requiredUserAttrs["user-gids"]; !ok {
 		t.Errorf("user user-gids key missing")
 	} else if !reflect.DeepEqual(val, []string{"name", "gid"}) {
 		t.Errorf("unexpected required attrs for user user-gids, got %v", val)
@@ -98,22 +107,27 @@ func TestInitRequiredUserAttrs(t *testing.T) {
 }
 
 func TestInitRequiredGroupAttrs(t *testing.T) {
-	if val, ok := requiredGroupAttrs["user-gid"]; !ok {
+	if val, ok := registry.requiredGroupAttrs["group-members"]; !ok {
+		t.Errorf("group group-members key missing")
+	} else if !reflect.DeepEqual(val, []string{"name", "gid"}) {
+		t.Errorf("unexpected required attrs for group group-members, got %v", val)
+	}
+	if val, ok := registry.requiredGroupAttrs["user-gid"]; !ok {
 		t.Errorf("group user-gid key missing")
 	} else if val != nil {
 		t.Errorf("unexpected required attrs for group user-gid, got %v", val)
 	}
-	if val, ok := requiredGroupAttrs["user-uid"]; !ok {
+	if val, ok := registry.requiredGroupAttrs["user-uid"]; !ok {
 		t.Errorf("group user-uid key missing")
 	} else if val != nil {
 		t.Errorf("unexpected required attrs for group user-uid, got %v", val)
 	}
-	if val, ok := requiredGroupAttrs["user-groups"]; !ok {
+	if val, ok := registry.requiredGroupAttrs["user-groups"]; !ok {
 		t.Errorf("group user-groups key missing")
 	} else if !reflect.DeepEqual(val, []string{"name", "gid"}) {
 		t.Errorf("unexpected required attrs for group user-groups, got %v", val)
 	}
-	if val, ok := requiredGroupAttrs["user-gids"]; !ok {
+	if val, ok := registry.requiredGroupAttrs["user-gids"]; !ok {
 		t.Errorf("group user-gids key missing")
 	} else if !reflect.DeepEqual(val, []string{"name", "gid"}) {
 		t.Errorf("unexpected required attrs for group user-gids, got %v", val)
@@ -121,7 +135,7 @@ func TestInitRequiredGroupAttrs(t *testing.T) {
 }
 
 func TestValidMappers(t *testing.T) {
-	expected := []string{"user-gid", "user-groups", "user-uid", "user-gids", "user-home", "user-all-groups"}
+	expected := []string{"user-gid", "user-groups", "user-uid", "user-gids", "user-home", "user-all-groups", "group-members", "group-gid", "uid-user", "gid-group", "passwd", "group", "user-info", "netgroup-members", "user-netgroups", "user-sudo", "user-ssh-keys"}
 	value := ValidMappers()
 	sort.Strings(value)
 	sort.Strings(expected)
@@ -130,6 +144,78 @@ func TestValidMappers(t *testing.T) {
 	}
 }
 
+func TestConfigMapName(t *testing.T) {
+	config := &config.Config{}
+	if name := ConfigMapName(config, "user-uid", "user-uid-map"); name != "user-uid-map" {
+		t.Errorf("Unexpected default name, got: %s", name)
+	}
+	config.ConfigMapNames = map[string]string{"user-uid": "uids"}
+	config.ConfigMapPrefix = "realm1-"
+	config.ConfigMapSuffix = "-v1"
+	if name := ConfigMapName(config, "user-uid", "user-uid-map"); name != "realm1-uids-v1" {
+		t.Errorf("Unexpected overridden name, got: %s", name)
+	}
+	if name := ConfigMapName(config, "user-gid", "user-gid-map"); name != "realm1-user-gid-map-v1" {
+		t.Errorf("Unexpected prefixed name, got: %s", name)
+	}
+	config.EnabledMappers = []string{"user-uid", "user-gid"}
+	names := ConfigMapNames(config, promslog.NewNopLogger())
+	if len(names) != 2 || names["user-uid"] != "realm1-uids-v1" || names["user-gid"] != "realm1-user-gid-map-v1" {
+		t.Errorf("Unexpected ConfigMap names, got: %v", names)
+	}
+}
+
+func TestParseMapperInstance(t *testing.T) {
+	name, mapperType := ParseMapperInstance("user-groups")
+	if name != "user-groups" || mapperType != "user-groups" {
+		t.Errorf("Unexpected unnamed mapper, got: %s %s", name, mapperType)
+	}
+	name, mapperType = ParseMapperInstance("active-groups:user-groups")
+	if name != "active-groups" || mapperType != "user-groups" {
+		t.Errorf("Unexpected named mapper, got: %s %s", name, mapperType)
+	}
+	enabled := []string{"user-uid", "active-groups:user-groups"}
+	if names := MapperNames(enabled); !reflect.DeepEqual(names, []string{"user-uid", "active-groups"}) {
+		t.Errorf("Unexpected mapper names, got: %v", names)
+	}
+	if mapperType := MapperType(enabled, "active-groups"); mapperType != "user-groups" {
+		t.Errorf("Unexpected mapper type, got: %s", mapperType)
+	}
+	if attrs := RequiredAttrs("group", []string{"active-groups:user-groups"}); !reflect.DeepEqual(attrs, []string{"name", "gid"}) {
+		t.Errorf("Unexpected required attrs of named mapper, got: %v", attrs)
+	}
+}
+
+func TestGetMappersInstances(t *testing.T) {
+	config := &config.Config{
+		EnabledMappers: []string{"user-uid", "uids:user-uid"},
+		UserAttrMap:    map[string]string{"name": "uid", "uid": "uidNumber"},
+		UserPrefix:     "foo-",
+		MappersUserAttrMap: map[string]map[string]string{
+			"uids": {"name": "cn"},
+		},
+		MappersUserPrefix: map[string]string{"uids": ""},
+	}
+	mappers := GetMappers(config, promslog.NewNopLogger())
+	if len(mappers) != 2 {
+		t.Fatalf("Unexpected number of mappers, got: %d", len(mappers))
+	}
+	sort.Slice(mappers, func(i, j int) bool { return mappers[i].Name() < mappers[j].Name() })
+	if mappers[0].Name() != "uids" || mappers[0].ConfigMapName() != "uids-map" {
+		t.Errorf("Unexpected named mapper, got: %s %s", mappers[0].Name(), mappers[0].ConfigMapName())
+	}
+	if mappers[1].Name() != "user-uid" || mappers[1].ConfigMapName() != "user-uid-map" {
+		t.Errorf("Unexpected mapper, got: %s %s", mappers[1].Name(), mappers[1].ConfigMapName())
+	}
+	instance := mappers[0].(*UserUID).config
+	if instance.UserAttrMap["name"] != "cn" || instance.UserAttrMap["uid"] != "uidNumber" || instance.UserPrefix != "" {
+		t.Errorf("Unexpected named mapper config, got: %v %s", instance.UserAttrMap, instance.UserPrefix)
+	}
+	if config.UserAttrMap["name"] != "uid" || config.UserPrefix != "foo-" || config.InstanceName != "" {
+		t.Errorf("Unexpected modification of config")
+	}
+}
+
 func TestParseDN(t *testing.T) {
 	value := ParseDN("cn=test,dc=test,dc=com")
 	if value != "test" {
@@ -166,3 +252,192 @@ func TestRequiredAttrs(t *testing.T) {
 		t.Errorf("Unexpected value for group required attrs\nExpected: %v\nGot: %v", expected, value)
 	}
 }
+
+func TestOptionalAttrs(t *testing.T) {
+	attrMap := map[string]string{
+		"name":  "uid",
+		"shell": "loginShell",
+	}
+	expected := []string{"shell"}
+	value := OptionalAttrs("user", []string{"user-uid", "passwd"}, attrMap)
+	if !reflect.DeepEqual(value, expected) {
+		t.Errorf("Unexpected value for user optional attrs\nExpected: %v\nGot: %v", expected, value)
+	}
+	expected = []string{"name", "shell"}
+	value = OptionalAttrs("user", []string{"user-info", "passwd"}, attrMap)
+	if !reflect.DeepEqual(value, expected) {
+		t.Errorf("Unexpected value for user optional attrs with all attributes\nExpected: %v\nGot: %v", expected, value)
+	}
+	expected = []string{}
+	value = OptionalAttrs("group", []string{"user-uid", "passwd"}, attrMap)
+	if !reflect.DeepEqual(value, expected) {
+		t.Errorf("Unexpected value for group optional attrs\nGot: %v", value)
+	}
+}
+
+func nestedGroupsSearchResults() (*ldapgo.SearchResult, *ldapgo.SearchResult) {
+	users := &ldapgo.SearchResult{
+		Entries: []*ldapgo.Entry{
+			ldapgo.NewEntry("cn=user1,ou=People,dc=test", map[string][]string{
+				"uid":       {"user1"},
+				"gidNumber": {"9999"},
+				"memberOf":  {"cn=child,ou=Groups,dc=test"},
+			}),
+			ldapgo.NewEntry("cn=user2,ou=People,dc=test", map[string][]string{
+				"uid":       {"user2"},
+				"gidNumber": {"9999"},
+				"memberOf":  {"cn=parent,ou=Groups,dc=test"},
+			}),
+			ldapgo.NewEntry("cn=user3,ou=People,dc=test", map[string][]string{
+				"uid":       {"user3"},
+				"gidNumber": {"9999"},
+			}),
+		},
+	}
+	groups := &ldapgo.SearchResult{
+		Entries: []*ldapgo.Entry{
+			ldapgo.NewEntry("cn=grandparent,ou=Groups,dc=test", map[string][]string{
+				"cn":        {"grandparent"},
+				"gidNumber": {"2002"},
+				"member":    {"cn=parent,ou=Groups,dc=test"},
+				"memberOf":  {"cn=child,ou=Groups,dc=test"},
+			}),
+			ldapgo.NewEntry("cn=parent,ou=Groups,dc=test", map[string][]string{
+				"cn":        {"parent"},
+				"gidNumber": {"2000"},
+				"member":    {"cn=child,ou=Groups,dc=test", "cn=user3,ou=People,dc=test"},
+				"memberOf":  {"cn=grandparent,ou=Groups,dc=test"},
+			}),
+			ldapgo.NewEntry("cn=child,ou=Groups,dc=test", map[string][]string{
+				"cn":        {"child"},
+				"gidNumber": {"2001"},
+				"member":    {"cn=user1,ou=People,dc=test", "cn=parent,ou=Groups,dc=test"},
+				"memberOf":  {"cn=parent,ou=Groups,dc=test"},
+			}),
+		},
+	}
+	return users, groups
+}
+
+func userGroupNames(data map[string][]Group) map[string][]string {
+	names := make(map[string][]string)
+	for user, groups := range data {
+		names[user] = []string{}
+		for _, group := range groups {
+			names[user] = append(names[user], group.name)
+		}
+		sort.Strings(names[user])
+	}
+	return names
+}
+
+func TestGetUserGroupsNested(t *testing.T) {
+	users, groups := nestedGroupsSearchResults()
+	tests := []struct {
+		scheme   string
+		depth    int
+		expected map[string][]string
+	}{
+		{
+			scheme: "member",
+			depth:  10,
+			expected: map[string][]string{
+				"user1": {"child", "grandparent", "parent"},
+				"user2": {},
+				"user3": {"child", "grandparent", "parent"},
+			},
+		},
+		{
+			scheme: "member",
+			depth:  1,
+			expected: map[string][]string{
+				"user1": {"child", "parent"},
+				"user2": {},
+				"user3": {"child", "grandparent", "parent"},
+			},
+		},
+		{
+			scheme: "member",
+			depth:  0,
+			expected: map[string][]string{
+				"user1": {"child"},
+				"user2": {},
+				"user3": {"parent"},
+			},
+		},
+		{
+			scheme: "memberof",
+			depth:  10,
+			expected: map[string][]string{
+				"user1": {"child", "grandparent", "parent"},
+				"user2": {"child", "grandparent", "parent"},
+				"user3": {},
+			},
+		},
+		{
+			scheme: "memberof",
+			depth:  0,
+			expected: map[string][]string{
+				"user1": {"child"},
+				"user2": {"parent"},
+				"user3": {},
+			},
+		},
+	}
+	for _, test := range tests {
+		mockConfig := &config.Config{
+			MemberScheme:      test.scheme,
+			NestedGroups:      true,
+			NestedGroupsDepth: test.depth,
+			UserAttrMap: map[string]string{
+				"name": "uid",
+				"gid":  "gidNumber",
+			},
+			GroupAttrMap: map[string]string{
+				"name": "cn",
+				"gid":  "gidNumber",
+			},
+		}
+		data, err := GetUserGroups(users, groups, "nested", mockConfig, promslog.NewNopLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value := userGroupNames(data); !reflect.DeepEqual(value, test.expected) {
+			t.Errorf("Unexpected nested groups for scheme %s depth %d\nExpected: %v\nGot: %v", test.scheme, test.depth, test.expected, value)
+		}
+		// child and parent are members of each other, and with memberof child, parent and grandparent form a cycle
+		if val := testutil.ToFloat64(metrics.MetricGroupCycles.WithLabelValues("nested")); val != 1 {
+			t.Errorf("Unexpected group cycles for scheme %s, got: %v", test.scheme, val)
+		}
+	}
+}
+
+func TestGetUserGroupsNestedInChain(t *testing.T) {
+	users, groups := nestedGroupsSearchResults()
+	mockConfig := &config.Config{
+		MemberScheme:        "memberof",
+		NestedGroups:        true,
+		NestedGroupsInChain: true,
+		UserAttrMap: map[string]string{
+			"name": "uid",
+			"gid":  "gidNumber",
+		},
+		GroupAttrMap: map[string]string{
+			"name": "cn",
+			"gid":  "gidNumber",
+		},
+	}
+	// With in chain the group member attribute already contains all nested users
+	data, err := GetUserGroups(users, groups, "in-chain", mockConfig, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		"user1": {"child"},
+		"user2": {},
+		"user3": {"parent"},
+	}
+	if value := userGroupNames(data); !reflect.DeepEqual(value, expected) {
+		t.Errorf("Unexpected in chain groups\nExpected: %v\nGot: %v", expected, value)
+	}
+}
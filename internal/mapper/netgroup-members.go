@@ -0,0 +1,63 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+func init() {
+	registerMapper("netgroup-members", nil, nil, NewNetgroupMembersMapper)
+	registerNetgroupMapper("netgroup-members")
+}
+
+func NewNetgroupMembersMapper(config *config.Config, logger *slog.Logger) Mapper {
+	return &NetgroupMembers{
+		config: config,
+		logger: logger,
+	}
+}
+
+type NetgroupMembers struct {
+	config *config.Config
+	logger *slog.Logger
+}
+
+func (m NetgroupMembers) Name() string {
+	return instanceName(m.config, "netgroup-members")
+}
+
+func (m NetgroupMembers) ConfigMapName() string {
+	return ConfigMapName(m.config, m.Name(), "netgroup-members-map")
+}
+
+func (m NetgroupMembers) GetData(users *ldap.SearchResult, netgroups *ldap.SearchResult) (map[string]string, error) {
+	m.logger.Debug("Mapper running")
+	netgroupMembers := make(map[string]string)
+	for name, members := range GetNetgroupMembers(netgroups, m.Name(), m.logger) {
+		netgroupUsers := []string{}
+		for _, member := range members {
+			netgroupUsers = append(netgroupUsers, fmt.Sprintf("%s%s", m.config.UserPrefix, member))
+		}
+		netgroupUsersJSON, _ := json.Marshal(netgroupUsers)
+		netgroupMembers[name] = string(netgroupUsersJSON)
+	}
+	m.logger.Debug("Mapper complete", "netgroup-members", len(netgroupMembers))
+	return netgroupMembers, nil
+}
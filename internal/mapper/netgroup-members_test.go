@@ -0,0 +1,57 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"testing"
+
+	"github.com/OSC/k8-ldap-configmap/internal/ldap"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestGetNetgroupMembersMapper(t *testing.T) {
+	mapper := NewNetgroupMembersMapper(_config, promslog.NewNopLogger())
+	l, err := ldap.LDAPConnect(_config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	netgroups, err := ldap.LDAPNetgroups(l, _config.NetgroupFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := mapper.GetData(nil, netgroups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 3 {
+		t.Errorf("Unexpected length of data, got: %d", len(data))
+	}
+	expected := `["testuser1","testuser2","testuser3"]`
+	if val, ok := data["netgroup1"]; !ok {
+		t.Errorf("netgroup1 not found in data")
+	} else if val != expected {
+		t.Errorf("Unexpected value for netgroup1\nExpected: %s\nGot: %s", expected, val)
+	}
+	if val, ok := data["netgroup2"]; !ok {
+		t.Errorf("netgroup2 not found in data")
+	} else if val != expected {
+		t.Errorf("Unexpected value for netgroup2\nExpected: %s\nGot: %s", expected, val)
+	}
+	expected = `[]`
+	if val, ok := data["netgroup3"]; !ok {
+		t.Errorf("netgroup3 not found in data")
+	} else if val != expected {
+		t.Errorf("Unexpected value for netgroup3\nExpected: %s\nGot: %s", expected, val)
+	}
+}
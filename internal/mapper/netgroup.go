@@ -0,0 +1,115 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/OSC/k8-ldap-configmap/internal/metrics"
+	"github.com/OSC/k8-ldap-configmap/internal/utils"
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+var (
+	netgroupMappers = []string{}
+)
+
+// registerNetgroupMapper marks a mapper as using netgroups, these mappers are passed
+// the netgroup search results in place of the group search results.
+func registerNetgroupMapper(name string) {
+	netgroupMappers = append(netgroupMappers, name)
+}
+
+// UsesNetgroups returns true if the mapper expects netgroup search results
+func UsesNetgroups(name string) bool {
+	return utils.SliceContains(netgroupMappers, name)
+}
+
+// NetgroupsRequired returns true if any enabled mapper uses netgroups
+func NetgroupsRequired(enabledMappers []string) bool {
+	for _, mapper := range mapperTypes(enabledMappers) {
+		if UsesNetgroups(mapper) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetNetgroupMembers returns each netgroup name mapped to the sorted users of that netgroup.
+// Member netgroups are expanded and each netgroup is only expanded once so cycles terminate,
+// cycles are logged and counted for the mapper.
+func GetNetgroupMembers(netgroups *ldap.SearchResult, mapperName string, logger *slog.Logger) map[string][]string {
+	triples := make(map[string][]string)
+	children := make(map[string][]string)
+	names := []string{}
+	for _, entry := range netgroups.Entries {
+		name := entry.GetAttributeValue("cn")
+		names = append(names, name)
+		children[name] = entry.GetAttributeValues("memberNisNetgroup")
+		for _, triple := range entry.GetAttributeValues("nisNetgroupTriple") {
+			if user := netgroupTripleUser(triple); user != "" {
+				triples[name] = append(triples[name], user)
+			}
+		}
+	}
+	cycles := findCycles(names, children)
+	for _, cycle := range cycles {
+		logger.Warn("Nested netgroup cycle found", "netgroups", strings.Join(append(cycle, cycle[0]), " -> "))
+	}
+	metrics.MetricNetgroupCycles.WithLabelValues(mapperName).Set(float64(len(cycles)))
+	data := make(map[string][]string)
+	for _, name := range names {
+		users := []string{}
+		seen := map[string]bool{name: true}
+		queue := []string{name}
+		for len(queue) > 0 {
+			netgroup := queue[0]
+			queue = queue[1:]
+			for _, user := range triples[netgroup] {
+				if !utils.SliceContains(users, user) {
+					users = append(users, user)
+				}
+			}
+			for _, child := range children[netgroup] {
+				if seen[child] {
+					continue
+				}
+				seen[child] = true
+				queue = append(queue, child)
+			}
+		}
+		sort.Strings(users)
+		data[name] = users
+	}
+	return data
+}
+
+// netgroupTripleUser returns the user of a (host,user,domain) triple.
+// Empty and "-" users are wildcards or no user so are not returned.
+func netgroupTripleUser(triple string) string {
+	triple = strings.TrimSpace(triple)
+	triple = strings.TrimPrefix(triple, "(")
+	triple = strings.TrimSuffix(triple, ")")
+	fields := strings.Split(triple, ",")
+	if len(fields) != 3 {
+		return ""
+	}
+	user := strings.TrimSpace(fields[1])
+	if user == "-" {
+		return ""
+	}
+	return user
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"bytes"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/OSC/k8-ldap-configmap/internal/metrics"
+	ldapgo "github.com/go-ldap/ldap/v3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestGetNetgroupMembers(t *testing.T) {
+	netgroups := &ldapgo.SearchResult{
+		Entries: []*ldapgo.Entry{
+			ldapgo.NewEntry("cn=ng1,ou=Netgroup,dc=example,dc=com", map[string][]string{
+				"cn":                {"ng1"},
+				"nisNetgroupTriple": {"(host1,user2,example.com)", "( ,user1, )"},
+				"memberNisNetgroup": {"ng2"},
+			}),
+			ldapgo.NewEntry("cn=ng2,ou=Netgroup,dc=example,dc=com", map[string][]string{
+				"cn":                {"ng2"},
+				"nisNetgroupTriple": {"(-,user3,)", "(host1,user1,)"},
+				"memberNisNetgroup": {"ng3"},
+			}),
+			ldapgo.NewEntry("cn=ng3,ou=Netgroup,dc=example,dc=com", map[string][]string{
+				"cn":                {"ng3"},
+				"nisNetgroupTriple": {"(host1,-,)", "(host2,,)", "invalid"},
+				"memberNisNetgroup": {"ng1", "dne"},
+			}),
+		},
+	}
+	var logs bytes.Buffer
+	data := GetNetgroupMembers(netgroups, "netgroup-members", slog.New(slog.NewTextHandler(&logs, nil)))
+	expected := map[string][]string{
+		"ng1": {"user1", "user2", "user3"},
+		"ng2": {"user1", "user2", "user3"},
+		"ng3": {"user1", "user2", "user3"},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("Unexpected data\nExpected: %v\nGot: %v", expected, data)
+	}
+	if !strings.Contains(logs.String(), `level=WARN msg="Nested netgroup cycle found" netgroups="ng1 -> ng2 -> ng3 -> ng1"`) {
+		t.Errorf("Netgroup cycle not logged, got: %s", logs.String())
+	}
+	if val := testutil.ToFloat64(metrics.MetricNetgroupCycles.WithLabelValues("netgroup-members")); val != 1 {
+		t.Errorf("Unexpected netgroup cycles metric, got: %v", val)
+	}
+}
+
+func TestNetgroupsRequired(t *testing.T) {
+	if NetgroupsRequired([]string{"user-uid", "user-gid"}) {
+		t.Errorf("Netgroups should not be required")
+	}
+	if !NetgroupsRequired([]string{"user-uid", "user-netgroups"}) {
+		t.Errorf("Netgroups should be required")
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"log/slog"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+var (
+	// OpenShiftGroupRequiredAttrs are the group attributes required by OpenShift groups
+	OpenShiftGroupRequiredAttrs = []string{"name", "gid"}
+	// OpenShiftUserRequiredAttrs are the user attributes required by OpenShift groups,
+	// the user GID adds users to the group of their primary GID
+	OpenShiftUserRequiredAttrs = []string{"name", "gid"}
+)
+
+// GetOpenShiftGroups returns the users of every group keyed by the group name, the users have the user prefix applied
+func GetOpenShiftGroups(users *ldap.SearchResult, groups *ldap.SearchResult, config *config.Config, logger *slog.Logger) (map[string][]string, error) {
+	userGroups, err := GetUserGroups(users, groups, "openshift-groups", config, logger)
+	if err != nil {
+		return nil, err
+	}
+	groupMembers := GetGroupMembers(userGroups)
+	openshiftGroups := make(map[string][]string)
+	for _, entry := range groups.Entries {
+		name := entry.GetAttributeValue(config.GroupAttrMap["name"])
+		members := groupMembers[name]
+		if members == nil {
+			members = []string{}
+		}
+		openshiftGroups[name] = members
+	}
+	return openshiftGroups, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/OSC/k8-ldap-configmap/internal/ldap"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestGetOpenShiftGroups(t *testing.T) {
+	_config.MemberScheme = "memberof"
+	_config.UserPrefix = "foo-"
+	defer func() {
+		_config.UserPrefix = ""
+	}()
+	l, err := ldap.LDAPConnect(_config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := ldap.LDAPUsers(l, _config.UserFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := ldap.LDAPGroups(l, _config.GroupFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	openshiftGroups, err := GetOpenShiftGroups(users, groups, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(openshiftGroups) != 3 {
+		t.Errorf("Unexpected number of groups, got: %d", len(openshiftGroups))
+	}
+	expected := []string{"foo-testuser1", "foo-testuser2", "foo-testuser3", "foo-testuser4"}
+	if val, ok := openshiftGroups["testgroup2"]; !ok {
+		t.Errorf("testgroup2 not found in groups")
+	} else if !reflect.DeepEqual(val, expected) {
+		t.Errorf("Unexpected value for testgroup2\nExpected: %v\nGot: %v", expected, val)
+	}
+}
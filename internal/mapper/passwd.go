@@ -0,0 +1,97 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+var (
+	nssFieldReplacer = strings.NewReplacer(":", "", "\n", "")
+)
+
+func init() {
+	registerMapper("passwd", []string{"name", "uid", "gid", "home"}, nil, NewPasswdMapper)
+	registerOptionalAttrs("passwd", []string{"gecos", "shell"}, nil)
+}
+
+func NewPasswdMapper(config *config.Config, logger *slog.Logger) Mapper {
+	return &Passwd{
+		config: config,
+		logger: logger,
+	}
+}
+
+type Passwd struct {
+	config *config.Config
+	logger *slog.Logger
+}
+
+func (m Passwd) Name() string {
+	return instanceName(m.config, "passwd")
+}
+
+func (m Passwd) ConfigMapName() string {
+	return ConfigMapName(m.config, m.Name(), "passwd-map")
+}
+
+func (m Passwd) GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error) {
+	m.logger.Debug("Mapper running")
+	lines := []string{}
+	for _, entry := range users.Entries {
+		name := entry.GetAttributeValue(m.config.UserAttrMap["name"])
+		if name == "" {
+			m.logger.Debug("Skipping user without name", "dn", entry.DN)
+			continue
+		}
+		fields := []string{
+			name,
+			"x",
+			entry.GetAttributeValue(m.config.UserAttrMap["uid"]),
+			entry.GetAttributeValue(m.config.UserAttrMap["gid"]),
+			nssAttributeValue(entry, m.config.UserAttrMap, "gecos"),
+			entry.GetAttributeValue(m.config.UserAttrMap["home"]),
+			nssAttributeValue(entry, m.config.UserAttrMap, "shell"),
+		}
+		for i, field := range fields {
+			fields[i] = nssFieldReplacer.Replace(field)
+		}
+		lines = append(lines, strings.Join(fields, ":"))
+	}
+	sort.Strings(lines)
+	m.logger.Debug("Mapper complete", "passwd", len(lines))
+	return map[string]string{"passwd": nssFile(lines)}, nil
+}
+
+// nssAttributeValue returns the value of an optional attribute, empty if the
+// attribute is not defined in the attribute map.
+func nssAttributeValue(entry *ldap.Entry, attrMap map[string]string, key string) string {
+	attr, ok := attrMap[key]
+	if !ok {
+		return ""
+	}
+	return entry.GetAttributeValue(attr)
+}
+
+func nssFile(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"testing"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	ldapgo "github.com/go-ldap/ldap/v3"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestGetPasswd(t *testing.T) {
+	users := &ldapgo.SearchResult{
+		Entries: []*ldapgo.Entry{
+			ldapgo.NewEntry("uid=testuser2,ou=people,dc=example,dc=com", map[string][]string{
+				"uid":           {"testuser2"},
+				"uidNumber":     {"1001"},
+				"gidNumber":     {"1000"},
+				"gecos":         {"Test: User 2"},
+				"homeDirectory": {"/home/testuser2"},
+				"loginShell":    {"/bin/bash"},
+			}),
+			ldapgo.NewEntry("uid=testuser1,ou=people,dc=example,dc=com", map[string][]string{
+				"uid":           {"testuser1"},
+				"uidNumber":     {"1000"},
+				"gidNumber":     {"1000"},
+				"homeDirectory": {"/home/testuser1"},
+			}),
+		},
+	}
+	mockConfig := &config.Config{
+		UserPrefix: "user-",
+		UserAttrMap: map[string]string{
+			"name":  "uid",
+			"uid":   "uidNumber",
+			"gid":   "gidNumber",
+			"home":  "homeDirectory",
+			"gecos": "gecos",
+			"shell": "loginShell",
+		},
+	}
+	mapper := NewPasswdMapper(mockConfig, promslog.NewNopLogger())
+	data, err := mapper.GetData(users, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "testuser1:x:1000:1000::/home/testuser1:\ntestuser2:x:1001:1000:Test User 2:/home/testuser2:/bin/bash\n"
+	if len(data) != 1 {
+		t.Errorf("Unexpected length of data, got: %d", len(data))
+	}
+	if val, ok := data["passwd"]; !ok {
+		t.Errorf("passwd not found in data")
+	} else if val != expected {
+		t.Errorf("Unexpected value for passwd\nExpected: %q\nGot: %q", expected, val)
+	}
+
+	delete(mockConfig.UserAttrMap, "shell")
+	data, err = mapper.GetData(users, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "testuser1:x:1000:1000::/home/testuser1:\ntestuser2:x:1001:1000:Test User 2:/home/testuser2:\n"
+	if val := data["passwd"]; val != expected {
+		t.Errorf("Unexpected value for passwd without shell\nExpected: %q\nGot: %q", expected, val)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	"github.com/OSC/k8-ldap-configmap/internal/metrics"
+	"github.com/OSC/k8-ldap-configmap/internal/utils"
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+const (
+	defaultKeyTemplate = "{{.username}}"
+)
+
+var (
+	templateFuncs = template.FuncMap{
+		"join": func(elems []string, sep string) string {
+			return strings.Join(elems, sep)
+		},
+	}
+)
+
+// ParseTemplateMapper parses a template mapper definition such as
+// {"name":"user-project","key":"{{.username}}","value":"/fs/ess/{{.gid}}/{{.name}}"}
+func ParseTemplateMapper(definition string) (config.TemplateMapper, error) {
+	var m config.TemplateMapper
+	if err := json.Unmarshal([]byte(definition), &m); err != nil {
+		return m, fmt.Errorf("template mapper %s is not valid JSON: %s", definition, err.Error())
+	}
+	if m.Name == "" {
+		return m, fmt.Errorf("template mapper %s is missing name", definition)
+	}
+	if _, ok := registry.factories[m.Name]; ok && !utils.SliceContains(registry.templateMappers, m.Name) {
+		return m, fmt.Errorf("template mapper name %s conflicts with an existing mapper", m.Name)
+	}
+	if m.KeyTemplate == "" {
+		m.KeyTemplate = defaultKeyTemplate
+	}
+	if m.ValueTemplate == "" {
+		return m, fmt.Errorf("template mapper %s is missing value", m.Name)
+	}
+	if m.ConfigMapName == "" {
+		m.ConfigMapName = fmt.Sprintf("%s-map", m.Name)
+	}
+	if _, err := parseTemplate(m.Name, "key", m.KeyTemplate); err != nil {
+		return m, err
+	}
+	if _, err := parseTemplate(m.Name, "value", m.ValueTemplate); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+func parseTemplate(name string, templateType string, text string) (*template.Template, error) {
+	tmpl, err := template.New(fmt.Sprintf("%s-%s", name, templateType)).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("template mapper %s %s template is not valid: %s", name, templateType, err.Error())
+	}
+	return tmpl, nil
+}
+
+// RegisterTemplateMappers registers each template mapper so it can be used like any other mapper.
+// Template mappers from previous calls are removed first.
+func RegisterTemplateMappers(definitions []config.TemplateMapper) {
+	registry.RegisterTemplateMappers(definitions)
+}
+
+// RegisterTemplateMappers registers each template mapper in the registry,
+// template mappers from previous calls are removed first.
+func (r *Registry) RegisterTemplateMappers(definitions []config.TemplateMapper) {
+	r.unregisterMappers(r.templateMappers)
+	r.templateMappers = []string{}
+	for _, definition := range definitions {
+		d := definition
+		r.registerMapper(d.Name, []string{"name", "gid"}, []string{"name", "gid"}, func(config *config.Config, logger *slog.Logger) Mapper {
+			return NewTemplateMapper(d, config, logger)
+		})
+		r.registerOptionalAttrs(d.Name, []string{"*"}, nil)
+		r.templateMappers = append(r.templateMappers, d.Name)
+	}
+}
+
+// NewTemplateMapper returns a template mapper, the definition is expected to
+// have been validated by ParseTemplateMapper
+func NewTemplateMapper(definition config.TemplateMapper, config *config.Config, logger *slog.Logger) Mapper {
+	keyTemplate, _ := parseTemplate(definition.Name, "key", definition.KeyTemplate)
+	valueTemplate, _ := parseTemplate(definition.Name, "value", definition.ValueTemplate)
+	return &Template{
+		definition:    definition,
+		keyTemplate:   keyTemplate,
+		valueTemplate: valueTemplate,
+		config:        config,
+		logger:        logger,
+	}
+}
+
+type Template struct {
+	definition    config.TemplateMapper
+	keyTemplate   *template.Template
+	valueTemplate *template.Template
+	config        *config.Config
+	logger        *slog.Logger
+}
+
+func (m Template) Name() string {
+	return instanceName(m.config, m.definition.Name)
+}
+
+func (m Template) ConfigMapName() string {
+	return ConfigMapName(m.config, m.Name(), m.definition.ConfigMapName)
+}
+
+func (m Template) GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error) {
+	m.logger.Debug("Mapper running")
+	if m.keyTemplate == nil || m.valueTemplate == nil {
+		return nil, fmt.Errorf("template mapper %s has invalid templates", m.definition.Name)
+	}
+	userGroups, err := GetUserGroups(users, groups, m.Name(), m.config, m.logger)
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]string)
+	for _, entry := range users.Entries {
+		username := fmt.Sprintf("%s%s", m.config.UserPrefix, entry.GetAttributeValue(m.config.UserAttrMap["name"]))
+		templateData := make(map[string]interface{})
+		for key, attr := range m.config.UserAttrMap {
+			templateData[key] = entry.GetAttributeValue(attr)
+		}
+		// Sort groups by name so groups, groupNames and gids stay aligned
+		sortedGroups := append([]Group{}, userGroups[username]...)
+		sort.Slice(sortedGroups, func(i, j int) bool { return sortedGroups[i].name < sortedGroups[j].name })
+		groupNames := make([]string, len(sortedGroups))
+		gids := make([]string, len(sortedGroups))
+		for i, group := range sortedGroups {
+			groupNames[i] = group.name
+			gids[i] = strconv.Itoa(group.gid)
+		}
+		templateData["username"] = username
+		templateData["groups"] = sortedGroups
+		templateData["groupNames"] = groupNames
+		templateData["gids"] = gids
+		key, err := executeTemplate(m.keyTemplate, templateData)
+		if err != nil {
+			m.logger.Error("Unable to execute key template", "user", username, "err", err)
+			metrics.MetricErrorsTotal.WithLabelValues(m.Name()).Inc()
+			continue
+		}
+		if key == "" {
+			m.logger.Debug("Skipping user with empty key", "user", username)
+			continue
+		}
+		value, err := executeTemplate(m.valueTemplate, templateData)
+		if err != nil {
+			m.logger.Error("Unable to execute value template", "user", username, "err", err)
+			metrics.MetricErrorsTotal.WithLabelValues(m.Name()).Inc()
+			continue
+		}
+		data[key] = value
+	}
+	m.logger.Debug("Mapper complete", "keys", len(data))
+	return data, nil
+}
+
+func executeTemplate(tmpl *template.Template, data map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
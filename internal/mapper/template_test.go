@@ -0,0 +1,101 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"testing"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	"github.com/OSC/k8-ldap-configmap/internal/ldap"
+	"github.com/OSC/k8-ldap-configmap/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestParseTemplateMapper(t *testing.T) {
+	m, err := ParseTemplateMapper(`{"name":"user-project","value":"/fs/ess/{{.gid}}/{{.name}}"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.KeyTemplate != defaultKeyTemplate {
+		t.Errorf("Unexpected key template, got: %s", m.KeyTemplate)
+	}
+	if m.ConfigMapName != "user-project-map" {
+		t.Errorf("Unexpected ConfigMap name, got: %s", m.ConfigMapName)
+	}
+	invalid := []string{
+		`name=user-project`,
+		`{"value":"{{.name}}"}`,
+		`{"name":"user-project"}`,
+		`{"name":"user-uid","value":"{{.name}}"}`,
+		`{"name":"user-project","value":"{{.name"}`,
+		`{"name":"user-project","key":"{{foo .name}}","value":"{{.name}}"}`,
+	}
+	for _, definition := range invalid {
+		if _, err := ParseTemplateMapper(definition); err == nil {
+			t.Errorf("Expected error parsing %s", definition)
+		}
+	}
+}
+
+func TestGetTemplate(t *testing.T) {
+	_config.MemberScheme = "memberof"
+	l, err := ldap.LDAPConnect(_config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := ldap.LDAPUsers(l, _config.UserFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := ldap.LDAPGroups(l, _config.GroupFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapper := NewTemplateMapper(config.TemplateMapper{
+		Name:          "user-project",
+		KeyTemplate:   "{{.username}}",
+		ValueTemplate: `/fs/ess/{{.gid}}/{{.name}}:{{join .groupNames ","}}:{{range .groups}}{{.GID}}{{end}}:{{join .gids ","}}`,
+		ConfigMapName: "user-project-map",
+	}, _config, promslog.NewNopLogger())
+	data, err := mapper.GetData(users, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 4 {
+		t.Errorf("Unexpected length of data, got: %d", len(data))
+	}
+	expected := "/fs/ess/1001/testuser1:testgroup1,testgroup2:10011000:1001,1000"
+	if val, ok := data["testuser1"]; !ok {
+		t.Errorf("testuser1 not found in data")
+	} else if val != expected {
+		t.Errorf("Unexpected value for testuser1\nExpected: %s\nGot: %s", expected, val)
+	}
+	mapper = NewTemplateMapper(config.TemplateMapper{
+		Name:          "user-missing",
+		KeyTemplate:   "{{.username}}",
+		ValueTemplate: "{{.shell}}",
+		ConfigMapName: "user-missing-map",
+	}, _config, promslog.NewNopLogger())
+	data, err = mapper.GetData(users, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 0 {
+		t.Errorf("Unexpected length of data, got: %d", len(data))
+	}
+	if val := testutil.ToFloat64(metrics.MetricErrorsTotal.WithLabelValues("user-missing")); val != 4 {
+		t.Errorf("Unexpected errors, got: %v", val)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"testing"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	"github.com/OSC/k8-ldap-configmap/internal/metrics"
+	ldapgo "github.com/go-ldap/ldap/v3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestGetUIDUsers(t *testing.T) {
+	users := &ldapgo.SearchResult{
+		Entries: []*ldapgo.Entry{
+			ldapgo.NewEntry("uid=testuser2,ou=people,dc=example,dc=com", map[string][]string{
+				"uid":       {"testuser2"},
+				"uidNumber": {"1000"},
+			}),
+			ldapgo.NewEntry("uid=testuser1,ou=people,dc=example,dc=com", map[string][]string{
+				"uid":       {"testuser1"},
+				"uidNumber": {"1000"},
+			}),
+			ldapgo.NewEntry("uid=testuser3,ou=people,dc=example,dc=com", map[string][]string{
+				"uid":       {"testuser3"},
+				"uidNumber": {"1001"},
+			}),
+		},
+	}
+	mockConfig := &config.Config{
+		UserPrefix: "user-",
+		UserAttrMap: map[string]string{
+			"name": "uid",
+			"uid":  "uidNumber",
+		},
+	}
+	mapper := NewUIDUserMapper(mockConfig, promslog.NewNopLogger())
+	data, err := mapper.GetData(users, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2 {
+		t.Errorf("Unexpected length of data, got: %d", len(data))
+	}
+	if val, ok := data["1000"]; !ok {
+		t.Errorf("1000 not found in data")
+	} else if val != "user-testuser1" {
+		t.Errorf("Unexpected value for 1000, got: %s", val)
+	}
+	if val, ok := data["1001"]; !ok {
+		t.Errorf("1001 not found in data")
+	} else if val != "user-testuser3" {
+		t.Errorf("Unexpected value for 1001, got: %s", val)
+	}
+	if val := testutil.ToFloat64(metrics.MetricCollisions.WithLabelValues("uid-user")); val != 1 {
+		t.Errorf("Unexpected collisions, got: %v", val)
+	}
+}
@@ -39,16 +39,16 @@ type UserAllGroups struct {
 }
 
 func (m UserAllGroups) Name() string {
-	return "user-all-groups"
+	return instanceName(m.config, "user-all-groups")
 }
 
 func (m UserAllGroups) ConfigMapName() string {
-	return "user-all-groups-map"
+	return ConfigMapName(m.config, m.Name(), "user-all-groups-map")
 }
 
 func (m UserAllGroups) GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error) {
 	m.logger.Debug("Mapper running")
-	data, err := GetUserGroups(users, groups, m.config, m.logger)
+	data, err := GetUserGroups(users, groups, m.Name(), m.config, m.logger)
 	if err != nil {
 		return nil, err
 	}
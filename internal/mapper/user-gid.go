@@ -38,11 +38,11 @@ type UserGID struct {
 }
 
 func (m UserGID) Name() string {
-	return "user-gid"
+	return instanceName(m.config, "user-gid")
 }
 
 func (m UserGID) ConfigMapName() string {
-	return "user-gid-map"
+	return ConfigMapName(m.config, m.Name(), "user-gid-map")
 }
 
 func (m UserGID) GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error) {
@@ -40,16 +40,16 @@ type UserGIDs struct {
 }
 
 func (m UserGIDs) Name() string {
-	return "user-gids"
+	return instanceName(m.config, "user-gids")
 }
 
 func (m UserGIDs) ConfigMapName() string {
-	return "user-gids-map"
+	return ConfigMapName(m.config, m.Name(), "user-gids-map")
 }
 
 func (m UserGIDs) GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error) {
 	m.logger.Debug("Mapper running")
-	data, err := GetUserGroups(users, groups, m.config, m.logger)
+	data, err := GetUserGroups(users, groups, m.Name(), m.config, m.logger)
 	if err != nil {
 		return nil, err
 	}
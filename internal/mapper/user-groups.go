@@ -39,16 +39,16 @@ type UserGroups struct {
 }
 
 func (m UserGroups) Name() string {
-	return "user-groups"
+	return instanceName(m.config, "user-groups")
 }
 
 func (m UserGroups) ConfigMapName() string {
-	return "user-groups-map"
+	return ConfigMapName(m.config, m.Name(), "user-groups-map")
 }
 
 func (m UserGroups) GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error) {
 	m.logger.Debug("Mapper running")
-	data, err := GetUserGroups(users, groups, m.config, m.logger)
+	data, err := GetUserGroups(users, groups, m.Name(), m.config, m.logger)
 	if err != nil {
 		return nil, err
 	}
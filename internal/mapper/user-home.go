@@ -38,11 +38,11 @@ type UserHome struct {
 }
 
 func (m UserHome) Name() string {
-	return "user-home"
+	return instanceName(m.config, "user-home")
 }
 
 func (m UserHome) ConfigMapName() string {
-	return "user-home-map"
+	return ConfigMapName(m.config, m.Name(), "user-home-map")
 }
 
 func (m UserHome) GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error) {
@@ -0,0 +1,81 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+func init() {
+	registerMapper("user-info", []string{"name", "uid", "gid"}, []string{"name", "gid"}, NewUserInfoMapper)
+	registerOptionalAttrs("user-info", []string{"*"}, nil)
+}
+
+func NewUserInfoMapper(config *config.Config, logger *slog.Logger) Mapper {
+	return &UserInfo{
+		config: config,
+		logger: logger,
+	}
+}
+
+type UserInfo struct {
+	config *config.Config
+	logger *slog.Logger
+}
+
+func (m UserInfo) Name() string {
+	return instanceName(m.config, "user-info")
+}
+
+func (m UserInfo) ConfigMapName() string {
+	return ConfigMapName(m.config, m.Name(), "user-info-map")
+}
+
+func (m UserInfo) GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error) {
+	m.logger.Debug("Mapper running")
+	data, err := GetUserGroups(users, groups, m.Name(), m.config, m.logger)
+	if err != nil {
+		return nil, err
+	}
+	userInfos := make(map[string]string)
+	for _, entry := range users.Entries {
+		name := fmt.Sprintf("%s%s", m.config.UserPrefix, entry.GetAttributeValue(m.config.UserAttrMap["name"]))
+		info := make(map[string]interface{})
+		for key, attr := range m.config.UserAttrMap {
+			info[key] = entry.GetAttributeValue(attr)
+		}
+		// Sort groups by name so groups and gids stay aligned
+		userGroups := append([]Group{}, data[name]...)
+		sort.Slice(userGroups, func(i, j int) bool { return userGroups[i].name < userGroups[j].name })
+		groupNames := make([]string, len(userGroups))
+		groupGIDs := make([]string, len(userGroups))
+		for i, group := range userGroups {
+			groupNames[i] = group.name
+			groupGIDs[i] = strconv.Itoa(group.gid)
+		}
+		info["groups"] = groupNames
+		info["gids"] = groupGIDs
+		userInfoJSON, _ := json.Marshal(info)
+		userInfos[name] = string(userInfoJSON)
+	}
+	m.logger.Debug("Mapper complete", "user-info", len(userInfos))
+	return userInfos, nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"testing"
+
+	"github.com/OSC/k8-ldap-configmap/internal/ldap"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestGetUserInfoMemberOf(t *testing.T) {
+	_config.MemberScheme = "memberof"
+	mapper := NewUserInfoMapper(_config, promslog.NewNopLogger())
+	l, err := ldap.LDAPConnect(_config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := ldap.LDAPUsers(l, _config.UserFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := ldap.LDAPGroups(l, _config.GroupFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := mapper.GetData(users, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 4 {
+		t.Errorf("Unexpected length of data, got: %d", len(data))
+	}
+	expected := `{"gid":"1001","gids":["1001","1000"],"groups":["testgroup1","testgroup2"],"name":"testuser1","uid":"1000"}`
+	if val, ok := data["testuser1"]; !ok {
+		t.Errorf("testuser1 not found in data")
+	} else if val != expected {
+		t.Errorf("Unexpected value for testuser1\nExpected: %s\nGot: %s", expected, val)
+	}
+	expected = `{"gid":"1000","gids":["1000"],"groups":["testgroup2"],"name":"testuser4","uid":"1003"}`
+	if val, ok := data["testuser4"]; !ok {
+		t.Errorf("testuser4 not found in data")
+	} else if val != expected {
+		t.Errorf("Unexpected value for testuser4\nExpected: %s\nGot: %s", expected, val)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+func init() {
+	registerMapper("user-netgroups", nil, nil, NewUserNetgroupsMapper)
+	registerNetgroupMapper("user-netgroups")
+}
+
+func NewUserNetgroupsMapper(config *config.Config, logger *slog.Logger) Mapper {
+	return &UserNetgroups{
+		config: config,
+		logger: logger,
+	}
+}
+
+type UserNetgroups struct {
+	config *config.Config
+	logger *slog.Logger
+}
+
+func (m UserNetgroups) Name() string {
+	return instanceName(m.config, "user-netgroups")
+}
+
+func (m UserNetgroups) ConfigMapName() string {
+	return ConfigMapName(m.config, m.Name(), "user-netgroups-map")
+}
+
+func (m UserNetgroups) GetData(users *ldap.SearchResult, netgroups *ldap.SearchResult) (map[string]string, error) {
+	m.logger.Debug("Mapper running")
+	data := make(map[string][]string)
+	for name, members := range GetNetgroupMembers(netgroups, m.Name(), m.logger) {
+		for _, member := range members {
+			key := fmt.Sprintf("%s%s", m.config.UserPrefix, member)
+			data[key] = append(data[key], name)
+		}
+	}
+	userNetgroups := make(map[string]string)
+	for user, names := range data {
+		sort.Strings(names)
+		namesJSON, _ := json.Marshal(names)
+		userNetgroups[user] = string(namesJSON)
+	}
+	m.logger.Debug("Mapper complete", "user-netgroups", len(userNetgroups))
+	return userNetgroups, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"testing"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	ldapgo "github.com/go-ldap/ldap/v3"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestGetUserNetgroups(t *testing.T) {
+	netgroups := &ldapgo.SearchResult{
+		Entries: []*ldapgo.Entry{
+			ldapgo.NewEntry("cn=ng2,ou=Netgroup,dc=example,dc=com", map[string][]string{
+				"cn":                {"ng2"},
+				"nisNetgroupTriple": {"(,user2,)"},
+			}),
+			ldapgo.NewEntry("cn=ng1,ou=Netgroup,dc=example,dc=com", map[string][]string{
+				"cn":                {"ng1"},
+				"nisNetgroupTriple": {"(,user1,)"},
+				"memberNisNetgroup": {"ng2"},
+			}),
+		},
+	}
+	mockConfig := &config.Config{
+		UserPrefix: "user-",
+	}
+	mapper := NewUserNetgroupsMapper(mockConfig, promslog.NewNopLogger())
+	data, err := mapper.GetData(nil, netgroups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2 {
+		t.Errorf("Unexpected length of data, got: %d", len(data))
+	}
+	if val, ok := data["user-user1"]; !ok {
+		t.Errorf("user-user1 not found in data")
+	} else if val != `["ng1"]` {
+		t.Errorf("Unexpected value for user-user1, got: %s", val)
+	}
+	if val, ok := data["user-user2"]; !ok {
+		t.Errorf("user-user2 not found in data")
+	} else if val != `["ng1","ng2"]` {
+		t.Errorf("Unexpected value for user-user2, got: %s", val)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	"github.com/OSC/k8-ldap-configmap/internal/metrics"
+	ldap "github.com/go-ldap/ldap/v3"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	ValidSSHKeysFormats = []string{"json", "authorized_keys"}
+)
+
+func init() {
+	registerMapper("user-ssh-keys", []string{"name", "sshkey"}, nil, NewUserSSHKeysMapper)
+}
+
+func NewUserSSHKeysMapper(config *config.Config, logger *slog.Logger) Mapper {
+	return &UserSSHKeys{
+		config: config,
+		logger: logger,
+	}
+}
+
+type UserSSHKeys struct {
+	config *config.Config
+	logger *slog.Logger
+}
+
+func (m UserSSHKeys) Name() string {
+	return instanceName(m.config, "user-ssh-keys")
+}
+
+func (m UserSSHKeys) ConfigMapName() string {
+	return ConfigMapName(m.config, m.Name(), "user-ssh-keys-map")
+}
+
+func (m UserSSHKeys) GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error) {
+	m.logger.Debug("Mapper running")
+	userSSHKeys := make(map[string]string)
+	invalid := 0
+	for _, entry := range users.Entries {
+		name := fmt.Sprintf("%s%s", m.config.UserPrefix, entry.GetAttributeValue(m.config.UserAttrMap["name"]))
+		keys := []string{}
+		for _, value := range entry.GetAttributeValues(m.config.UserAttrMap["sshkey"]) {
+			key, err := authorizedKey(value)
+			if err != nil {
+				m.logger.Warn("Skipping invalid SSH public key", "user", name, "err", err)
+				invalid++
+				continue
+			}
+			keys = append(keys, key)
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		switch m.config.SSHKeysFormat {
+		case "authorized_keys":
+			userSSHKeys[name] = strings.Join(keys, "\n") + "\n"
+		default:
+			keysJSON, _ := json.Marshal(keys)
+			userSSHKeys[name] = string(keysJSON)
+		}
+	}
+	metrics.MetricInvalidSSHKeys.WithLabelValues(m.Name()).Set(float64(invalid))
+	m.logger.Debug("Mapper complete", "user-ssh-keys", len(userSSHKeys))
+	return userSSHKeys, nil
+}
+
+// authorizedKey parses a single authorized_keys line and returns it re-encoded with its options and comment.
+// Values with more than one line are rejected so a value cannot inject additional keys.
+func authorizedKey(value string) (string, error) {
+	pub, comment, options, rest, err := ssh.ParseAuthorizedKey([]byte(strings.TrimSpace(value)))
+	if err != nil {
+		return "", err
+	}
+	if len(bytes.TrimSpace(rest)) != 0 {
+		return "", fmt.Errorf("SSH public key value contains more than one line")
+	}
+	key := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(pub)))
+	if len(options) > 0 {
+		key = strings.Join(options, ",") + " " + key
+	}
+	if comment != "" {
+		key = key + " " + comment
+	}
+	return key, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	"github.com/OSC/k8-ldap-configmap/internal/metrics"
+	ldapgo "github.com/go-ldap/ldap/v3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/promslog"
+)
+
+const (
+	testSSHKey1 = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIH1lp3r96YbFQBoeL9mbFEtZ77e0O1eELYYhwWNal1vK user1@test"
+	testSSHKey2 = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBS2xgeDPk97Cisc9nBR36xDpJDRNePDTwcFe8HSjLtZ user2"
+	testSSHKey3 = `from="10.0.0.0/8",no-pty ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBS2xgeDPk97Cisc9nBR36xDpJDRNePDTwcFe8HSjLtZ user2@host`
+)
+
+func TestGetUserSSHKeys(t *testing.T) {
+	users := &ldapgo.SearchResult{
+		Entries: []*ldapgo.Entry{
+			ldapgo.NewEntry("uid=testuser1,ou=people,dc=example,dc=com", map[string][]string{
+				"uid":          {"testuser1"},
+				"sshPublicKey": {testSSHKey1, testSSHKey2 + "\n", testSSHKey3},
+			}),
+			ldapgo.NewEntry("uid=testuser2,ou=people,dc=example,dc=com", map[string][]string{
+				"uid":          {"testuser2"},
+				"sshPublicKey": {"ssh-ed25519 invalid", testSSHKey2},
+			}),
+			ldapgo.NewEntry("uid=testuser3,ou=people,dc=example,dc=com", map[string][]string{
+				"uid":          {"testuser3"},
+				"sshPublicKey": {"foobar", testSSHKey1 + "\n" + testSSHKey2},
+			}),
+			ldapgo.NewEntry("uid=testuser4,ou=people,dc=example,dc=com", map[string][]string{
+				"uid": {"testuser4"},
+			}),
+		},
+	}
+	mockConfig := &config.Config{
+		UserPrefix: "user-",
+		UserAttrMap: map[string]string{
+			"name":   "uid",
+			"sshkey": "sshPublicKey",
+		},
+		SSHKeysFormat: "json",
+	}
+	mapper := NewUserSSHKeysMapper(mockConfig, promslog.NewNopLogger())
+	data, err := mapper.GetData(users, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2 {
+		t.Errorf("Unexpected length of data, got: %d", len(data))
+	}
+	expected := `["` + testSSHKey1 + `","` + testSSHKey2 + `","` + strings.ReplaceAll(testSSHKey3, `"`, `\"`) + `"]`
+	if val, ok := data["user-testuser1"]; !ok {
+		t.Errorf("user-testuser1 not found in data")
+	} else if val != expected {
+		t.Errorf("Unexpected value for user-testuser1\nExpected: %s\nGot: %s", expected, val)
+	}
+	expected = `["` + testSSHKey2 + `"]`
+	if val, ok := data["user-testuser2"]; !ok {
+		t.Errorf("user-testuser2 not found in data")
+	} else if val != expected {
+		t.Errorf("Unexpected value for user-testuser2\nExpected: %s\nGot: %s", expected, val)
+	}
+	if val := testutil.ToFloat64(metrics.MetricInvalidSSHKeys.WithLabelValues("user-ssh-keys")); val != 3 {
+		t.Errorf("Unexpected invalid SSH keys, got: %v", val)
+	}
+
+	mockConfig.SSHKeysFormat = "authorized_keys"
+	data, err = mapper.GetData(users, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = testSSHKey1 + "\n" + testSSHKey2 + "\n" + testSSHKey3 + "\n"
+	if val, ok := data["user-testuser1"]; !ok {
+		t.Errorf("user-testuser1 not found in data")
+	} else if val != expected {
+		t.Errorf("Unexpected value for user-testuser1\nExpected: %s\nGot: %s", expected, val)
+	}
+}
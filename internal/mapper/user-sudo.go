@@ -0,0 +1,179 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/OSC/k8-ldap-configmap/internal/config"
+	"github.com/OSC/k8-ldap-configmap/internal/metrics"
+	"github.com/OSC/k8-ldap-configmap/internal/utils"
+	ldap "github.com/go-ldap/ldap/v3"
+)
+
+var (
+	sudoRolesMappers = []string{}
+)
+
+func init() {
+	registerMapper("user-sudo", []string{"name", "gid"}, []string{"name", "gid"}, NewUserSudoMapper)
+	registerOptionalAttrs("user-sudo", []string{"uid"}, nil)
+	sudoRolesMappers = append(sudoRolesMappers, "user-sudo")
+}
+
+// SudoRolesRequired returns true if any enabled mapper uses sudoRole entries
+func SudoRolesRequired(enabledMappers []string) bool {
+	for _, mapper := range mapperTypes(enabledMappers) {
+		if utils.SliceContains(sudoRolesMappers, mapper) {
+			return true
+		}
+	}
+	return false
+}
+
+type SudoRule struct {
+	Name       string   `json:"name"`
+	Hosts      []string `json:"hosts"`
+	Commands   []string `json:"commands"`
+	RunAsUsers []string `json:"runAsUsers"`
+}
+
+func NewUserSudoMapper(config *config.Config, logger *slog.Logger) Mapper {
+	return &UserSudo{
+		config: config,
+		logger: logger,
+	}
+}
+
+type UserSudo struct {
+	config *config.Config
+	logger *slog.Logger
+}
+
+func (m UserSudo) Name() string {
+	return instanceName(m.config, "user-sudo")
+}
+
+func (m UserSudo) ConfigMapName() string {
+	return ConfigMapName(m.config, m.Name(), "user-sudo-map")
+}
+
+func (m UserSudo) GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error) {
+	return m.GetSudoData(users, groups, &ldap.SearchResult{})
+}
+
+func (m UserSudo) GetSudoData(users *ldap.SearchResult, groups *ldap.SearchResult, sudoRoles *ldap.SearchResult) (map[string]string, error) {
+	m.logger.Debug("Mapper running")
+	userGroups, err := GetUserGroups(users, groups, m.Name(), m.config, m.logger)
+	if err != nil {
+		return nil, err
+	}
+	groupMembers := GetGroupMembers(userGroups)
+	gidMembers := make(map[string][]string)
+	for user, groups := range userGroups {
+		for _, group := range groups {
+			gid := fmt.Sprintf("%d", group.gid)
+			gidMembers[gid] = append(gidMembers[gid], user)
+		}
+	}
+	allUsers := make(map[string]struct{})
+	uidUsers := make(map[string][]string)
+	for _, entry := range users.Entries {
+		name := fmt.Sprintf("%s%s", m.config.UserPrefix, entry.GetAttributeValue(m.config.UserAttrMap["name"]))
+		allUsers[name] = struct{}{}
+		if uidAttr, ok := m.config.UserAttrMap["uid"]; ok {
+			uid := entry.GetAttributeValue(uidAttr)
+			uidUsers[uid] = append(uidUsers[uid], name)
+		}
+	}
+	matchUsers := func(sudoUser string) ([]string, bool) {
+		switch {
+		case sudoUser == "ALL":
+			matched := make([]string, 0, len(allUsers))
+			for user := range allUsers {
+				matched = append(matched, user)
+			}
+			return matched, true
+		case strings.HasPrefix(sudoUser, "%#"):
+			return gidMembers[strings.TrimPrefix(sudoUser, "%#")], true
+		case strings.HasPrefix(sudoUser, "%"):
+			return groupMembers[strings.TrimPrefix(sudoUser, "%")], true
+		case strings.HasPrefix(sudoUser, "#"):
+			return uidUsers[strings.TrimPrefix(sudoUser, "#")], true
+		case strings.HasPrefix(sudoUser, "+"):
+			return nil, false
+		default:
+			return []string{fmt.Sprintf("%s%s", m.config.UserPrefix, sudoUser)}, true
+		}
+	}
+	data := make(map[string][]SudoRule)
+	for _, entry := range sudoRoles.Entries {
+		rule := SudoRule{
+			Name:       entry.GetAttributeValue("cn"),
+			Hosts:      entry.GetAttributeValues("sudoHost"),
+			Commands:   entry.GetAttributeValues("sudoCommand"),
+			RunAsUsers: entry.GetAttributeValues("sudoRunAsUser"),
+		}
+		ruleUsers := make(map[string]struct{})
+		negatedUsers := make(map[string]struct{})
+		valid := true
+		for _, sudoUser := range entry.GetAttributeValues("sudoUser") {
+			negated := strings.HasPrefix(sudoUser, "!")
+			matched, ok := matchUsers(strings.TrimPrefix(sudoUser, "!"))
+			if !ok && negated {
+				// Users an unsupported negation removes are unknown so the whole rule is rejected
+				m.logger.Warn("Skipping sudoRole with unsupported negated sudoUser", "sudoRole", rule.Name, "sudoUser", sudoUser)
+				metrics.MetricErrorsTotal.WithLabelValues(m.Name()).Inc()
+				valid = false
+				break
+			} else if !ok {
+				m.logger.Debug("Skipping unsupported sudoUser", "sudoRole", rule.Name, "sudoUser", sudoUser)
+				continue
+			}
+			for _, user := range matched {
+				if negated {
+					negatedUsers[user] = struct{}{}
+				} else {
+					ruleUsers[user] = struct{}{}
+				}
+			}
+		}
+		if !valid {
+			continue
+		}
+		for user := range ruleUsers {
+			if _, ok := negatedUsers[user]; ok {
+				continue
+			}
+			if _, ok := allUsers[user]; !ok {
+				continue
+			}
+			data[user] = append(data[user], rule)
+		}
+	}
+	userSudo := make(map[string]string)
+	for user, rules := range data {
+		sort.Slice(rules, func(i, j int) bool {
+			return rules[i].Name < rules[j].Name
+		})
+		rulesJSON, _ := json.Marshal(rules)
+		userSudo[user] = string(rulesJSON)
+	}
+	m.logger.Debug("Mapper complete", "user-sudo", len(userSudo))
+	return userSudo, nil
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mapper
+
+import (
+	"testing"
+
+	"github.com/OSC/k8-ldap-configmap/internal/ldap"
+	"github.com/OSC/k8-ldap-configmap/internal/metrics"
+	ldapgo "github.com/go-ldap/ldap/v3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/promslog"
+)
+
+func TestGetUserSudo(t *testing.T) {
+	_config.MemberScheme = "memberof"
+	mapper := NewUserSudoMapper(_config, promslog.NewNopLogger())
+	l, err := ldap.LDAPConnect(_config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := ldap.LDAPUsers(l, _config.UserFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := ldap.LDAPGroups(l, _config.GroupFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sudoRoles, err := ldap.LDAPSudoRoles(l, _config.SudoersFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := mapper.(SudoRolesMapper).GetSudoData(users, groups, sudoRoles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 3 {
+		t.Errorf("Unexpected length of data, got: %d", len(data))
+	}
+	expected := `[{"name":"debug","hosts":["ALL"],"commands":["/usr/bin/kubectl debug"],"runAsUsers":["root"]}]`
+	if val, ok := data["testuser1"]; !ok {
+		t.Errorf("testuser1 not found in data")
+	} else if val != expected {
+		t.Errorf("Unexpected value for testuser1\nExpected: %s\nGot: %s", expected, val)
+	}
+	if val, ok := data["testuser2"]; !ok {
+		t.Errorf("testuser2 not found in data")
+	} else if val != expected {
+		t.Errorf("Unexpected value for testuser2\nExpected: %s\nGot: %s", expected, val)
+	}
+	expected = `[{"name":"restart","hosts":["host1","host2"],"commands":["/usr/bin/systemctl restart","/usr/bin/systemctl status"],"runAsUsers":[]}]`
+	if val, ok := data["testuser3"]; !ok {
+		t.Errorf("testuser3 not found in data")
+	} else if val != expected {
+		t.Errorf("Unexpected value for testuser3\nExpected: %s\nGot: %s", expected, val)
+	}
+	data, err = mapper.GetData(users, groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 0 {
+		t.Errorf("Unexpected length of data without sudo roles, got: %d", len(data))
+	}
+}
+
+func TestGetUserSudoNegation(t *testing.T) {
+	_config.MemberScheme = "memberof"
+	mapper := NewUserSudoMapper(_config, promslog.NewNopLogger())
+	l, err := ldap.LDAPConnect(_config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	users, err := ldap.LDAPUsers(l, _config.UserFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	groups, err := ldap.LDAPGroups(l, _config.GroupFilter, _config, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sudoRoles := &ldapgo.SearchResult{
+		Entries: []*ldapgo.Entry{
+			ldapgo.NewEntry("cn=all,ou=SUDOers,dc=test", map[string][]string{
+				"cn":          {"all"},
+				"sudoUser":    {"ALL", "!testuser2"},
+				"sudoHost":    {"ALL"},
+				"sudoCommand": {"ALL"},
+			}),
+			ldapgo.NewEntry("cn=group,ou=SUDOers,dc=test", map[string][]string{
+				"cn":          {"group"},
+				"sudoUser":    {"ALL", "!%testgroup1"},
+				"sudoHost":    {"ALL"},
+				"sudoCommand": {"ALL"},
+			}),
+			ldapgo.NewEntry("cn=netgroup,ou=SUDOers,dc=test", map[string][]string{
+				"cn":          {"netgroup"},
+				"sudoUser":    {"ALL", "!+netgroup1"},
+				"sudoHost":    {"ALL"},
+				"sudoCommand": {"ALL"},
+			}),
+		},
+	}
+	errors := testutil.ToFloat64(metrics.MetricErrorsTotal.WithLabelValues("user-sudo"))
+	data, err := mapper.(SudoRolesMapper).GetSudoData(users, groups, sudoRoles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val := testutil.ToFloat64(metrics.MetricErrorsTotal.WithLabelValues("user-sudo")) - errors; val != 1 {
+		t.Errorf("Unexpected errors for unsupported negation, got: %v", val)
+	}
+	expected := `[{"name":"all","hosts":["ALL"],"commands":["ALL"],"runAsUsers":[]}]`
+	if val, ok := data["testuser1"]; !ok {
+		t.Errorf("testuser1 not found in data")
+	} else if val != expected {
+		t.Errorf("Unexpected value for testuser1\nExpected: %s\nGot: %s", expected, val)
+	}
+	if val, ok := data["testuser2"]; ok {
+		t.Errorf("testuser2 should be negated, got: %s", val)
+	}
+	expected = `[{"name":"all","hosts":["ALL"],"commands":["ALL"],"runAsUsers":[]},{"name":"group","hosts":["ALL"],"commands":["ALL"],"runAsUsers":[]}]`
+	if val, ok := data["testuser3"]; !ok {
+		t.Errorf("testuser3 not found in data")
+	} else if val != expected {
+		t.Errorf("Unexpected value for testuser3\nExpected: %s\nGot: %s", expected, val)
+	}
+}
@@ -38,11 +38,11 @@ type UserUID struct {
 }
 
 func (m UserUID) Name() string {
-	return "user-uid"
+	return instanceName(m.config, "user-uid")
 }
 
 func (m UserUID) ConfigMapName() string {
-	return "user-uid-map"
+	return ConfigMapName(m.config, m.Name(), "user-uid-map")
 }
 
 func (m UserUID) GetData(users *ldap.SearchResult, groups *ldap.SearchResult) (map[string]string, error) {
@@ -45,6 +45,61 @@ var (
 		Name:      "errors_total",
 		Help:      "Total number of errors",
 	}, []string{"mapper"})
+	MetricNamespaceErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "namespace_errors_total",
+		Help:      "Total number of errors writing to a namespace",
+	}, []string{"namespace"})
+	MetricWritesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "writes_total",
+		Help:      "Total number of ConfigMap writes by action, either create, update or unchanged",
+	}, []string{"configmap", "action"})
+	MetricPrunedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "pruned_total",
+		Help:      "Total number of ConfigMaps pruned because their mapper is not enabled",
+	}, []string{"configmap", "mode"})
+	MetricCustomResourceWritesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "custom_resource_writes_total",
+		Help:      "Total number of custom resource, OpenShift group and Kyverno GlobalContextEntry writes by kind and action, either create, update, unchanged, delete or skipped",
+	}, []string{"kind", "action"})
+	MetricCustomResourceErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "custom_resource_errors_total",
+		Help:      "Total number of errors syncing custom resources, OpenShift groups and Kyverno GlobalContextEntries",
+	})
+	MetricGitErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "git_errors_total",
+		Help:      "Total number of errors committing to or pushing the Git repository",
+	})
+	MetricConflictsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "conflicts_total",
+		Help:      "Total number of server-side apply conflicts",
+	}, []string{"configmap"})
+	MetricCollisions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "collisions",
+		Help:      "Number of duplicate keys found during last mapper run",
+	}, []string{"mapper"})
+	MetricNetgroupCycles = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "netgroup_cycles",
+		Help:      "Number of nested netgroup cycles found during last mapper run",
+	}, []string{"mapper"})
+	MetricGroupCycles = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "group_cycles",
+		Help:      "Number of nested group cycles found during last mapper run",
+	}, []string{"mapper"})
+	MetricInvalidSSHKeys = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "invalid_ssh_keys",
+		Help:      "Number of malformed SSH public keys skipped during last mapper run",
+	}, []string{"mapper"})
 	MetricDuration = prometheus.NewGauge(prometheus.GaugeOpts{
 		Namespace: metricsNamespace,
 		Name:      "run_duration_seconds",
@@ -55,16 +110,26 @@ var (
 		Name:      "last_run_timestamp_seconds",
 		Help:      "Last timestamp of execution",
 	})
+	MetricLastSync = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "last_sync_timestamp_seconds",
+		Help:      "Last timestamp the mapper was successfully synced",
+	}, []string{"mapper"})
 	MetricConfigMapSize = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: metricsNamespace,
 		Name:      "size_bytes",
 		Help:      "Size of ConfigMap in bytes",
+	}, []string{"configmap", "namespace"})
+	MetricShards = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "shards",
+		Help:      "Number of shards the ConfigMap data is split across",
 	}, []string{"configmap"})
 	MetricConfigMapKeys = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: metricsNamespace,
 		Name:      "keys_count",
 		Help:      "Number of data keys in ConfigMap",
-	}, []string{"configmap"})
+	}, []string{"configmap", "namespace"})
 )
 
 func init() {
@@ -76,10 +141,23 @@ func MetricGathers(processMetrics bool) prometheus.Gatherers {
 	registry.MustRegister(metricBuildInfo)
 	registry.MustRegister(MetricError)
 	registry.MustRegister(MetricErrorsTotal)
+	registry.MustRegister(MetricNamespaceErrorsTotal)
+	registry.MustRegister(MetricWritesTotal)
+	registry.MustRegister(MetricPrunedTotal)
+	registry.MustRegister(MetricCustomResourceWritesTotal)
+	registry.MustRegister(MetricCustomResourceErrorsTotal)
+	registry.MustRegister(MetricGitErrorsTotal)
+	registry.MustRegister(MetricConflictsTotal)
+	registry.MustRegister(MetricCollisions)
+	registry.MustRegister(MetricNetgroupCycles)
+	registry.MustRegister(MetricGroupCycles)
+	registry.MustRegister(MetricInvalidSSHKeys)
 	registry.MustRegister(MetricDuration)
 	registry.MustRegister(MetricLastRun)
+	registry.MustRegister(MetricLastSync)
 	registry.MustRegister(MetricConfigMapSize)
 	registry.MustRegister(MetricConfigMapKeys)
+	registry.MustRegister(MetricShards)
 	gatherers := prometheus.Gatherers{registry}
 	if processMetrics {
 		gatherers = append(gatherers, prometheus.DefaultGatherer)
@@ -0,0 +1,250 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// FormatJSON writes the data of a mapper as one JSON object to <name>.json
+	FormatJSON = "json"
+	// FormatConfigMap writes each key of a mapper as a file in the <name> directory like a mounted ConfigMap
+	FormatConfigMap = "configmap"
+	// dataDirName is the symlink to the timestamped directory holding the keys, as used by mounted ConfigMaps
+	dataDirName = "..data"
+)
+
+// WriteFilesystem writes the data of a mapper to dir in the given format and returns the action taken,
+// either create, update or unchanged. Secret data is only readable by the owner.
+func WriteFilesystem(dir string, format string, name string, data map[string]string, secret bool, logger *slog.Logger) (string, error) {
+	filePerm := os.FileMode(0o644)
+	dirPerm := os.FileMode(0o755)
+	if secret {
+		filePerm = 0o600
+		dirPerm = 0o700
+	}
+	switch format {
+	case FormatJSON:
+		content, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return writeFile(filepath.Join(dir, fmt.Sprintf("%s.json", name)), append(content, '\n'), filePerm, logger)
+	case FormatConfigMap:
+		return writeKeys(filepath.Join(dir, name), data, dirPerm, filePerm, logger)
+	default:
+		return "", fmt.Errorf("unknown filesystem format %s", format)
+	}
+}
+
+// writeKeys writes the keys to dir with the same layout as a mounted ConfigMap so readers never see a partial update.
+// The keys are written to a new timestamped directory, the ..data symlink is atomically swapped to it and each key
+// is a symlink through ..data, the previous timestamped directory and symlinks of keys no longer in data are removed.
+func writeKeys(dir string, data map[string]string, dirPerm os.FileMode, filePerm os.FileMode, logger *slog.Logger) (string, error) {
+	for key := range data {
+		if msgs := validation.IsConfigMapKey(key); len(msgs) > 0 {
+			err := fmt.Errorf("invalid key %s: %s", key, strings.Join(msgs, ", "))
+			logger.Error("Unable to write key", "dir", dir, "err", err)
+			return "", err
+		}
+	}
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		logger.Error("Unable to create directory", "dir", dir, "err", err)
+		return "", err
+	}
+	dataLink := filepath.Join(dir, dataDirName)
+	action := "update"
+	previous, err := os.Readlink(dataLink)
+	if errors.Is(err, fs.ErrNotExist) {
+		action = "create"
+	} else if err != nil {
+		logger.Error("Unable to read data symlink", "path", dataLink, "err", err)
+		return "", err
+	} else if keysUnchanged(dir, filepath.Join(dir, previous), data, filePerm) {
+		return "unchanged", nil
+	}
+	tsDir, err := os.MkdirTemp(dir, time.Now().UTC().Format("..2006_01_02_15_04_05."))
+	if err != nil {
+		logger.Error("Unable to create timestamped directory", "dir", dir, "err", err)
+		return "", err
+	}
+	if err := writeDataDir(tsDir, data, dirPerm, filePerm); err != nil {
+		os.RemoveAll(tsDir)
+		logger.Error("Unable to write keys", "dir", tsDir, "err", err)
+		return "", err
+	}
+	if err := replaceSymlink(filepath.Base(tsDir), dataLink); err != nil {
+		os.RemoveAll(tsDir)
+		logger.Error("Unable to swap data symlink", "path", dataLink, "err", err)
+		return "", err
+	}
+	for key := range data {
+		keyPath := filepath.Join(dir, key)
+		target := filepath.Join(dataDirName, key)
+		if current, err := os.Readlink(keyPath); err == nil && current == target {
+			continue
+		}
+		if err := replaceSymlink(target, keyPath); err != nil {
+			logger.Error("Unable to create key symlink", "path", keyPath, "err", err)
+			return "", err
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if strings.HasPrefix(entry.Name(), "..") {
+			// Remove the previous and any abandoned timestamped directories
+			if !entry.IsDir() || path == tsDir {
+				continue
+			}
+			if err := os.RemoveAll(path); err != nil {
+				logger.Error("Unable to remove previous data directory", "path", path, "err", err)
+				return "", err
+			}
+			continue
+		}
+		if _, ok := data[entry.Name()]; ok || entry.IsDir() {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			logger.Error("Unable to remove stale key", "path", path, "err", err)
+			return "", err
+		}
+		logger.Debug("Removed stale key", "path", path)
+	}
+	return action, nil
+}
+
+// writeDataDir writes each key of data to a file in the timestamped directory
+func writeDataDir(tsDir string, data map[string]string, dirPerm os.FileMode, filePerm os.FileMode) error {
+	if err := os.Chmod(tsDir, dirPerm); err != nil {
+		return err
+	}
+	for key, value := range data {
+		path := filepath.Join(tsDir, key)
+		if err := os.WriteFile(path, []byte(value), filePerm); err != nil {
+			return err
+		}
+		if err := os.Chmod(path, filePerm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keysUnchanged returns true when dataDir holds exactly the keys of data with the same content and permissions
+// and the keys in dir are exactly the symlinks through ..data of those keys
+func keysUnchanged(dir string, dataDir string, data map[string]string, filePerm os.FileMode) bool {
+	entries, err := os.ReadDir(dataDir)
+	if err != nil || len(entries) != len(data) {
+		return false
+	}
+	for key, value := range data {
+		info, err := os.Stat(filepath.Join(dataDir, key))
+		if err != nil || info.Mode().Perm() != filePerm {
+			return false
+		}
+		existing, err := os.ReadFile(filepath.Join(dataDir, key))
+		if err != nil || !bytes.Equal(existing, []byte(value)) {
+			return false
+		}
+		if target, err := os.Readlink(filepath.Join(dir, key)); err != nil || target != filepath.Join(dataDirName, key) {
+			return false
+		}
+	}
+	entries, err = os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if _, ok := data[entry.Name()]; !ok && !strings.HasPrefix(entry.Name(), "..") && !entry.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+// replaceSymlink atomically points path at target by renaming a temporary symlink over it,
+// the temporary symlink starts with .. so it is never mistaken for a key
+func replaceSymlink(target string, path string) error {
+	tmp := filepath.Join(filepath.Dir(path), fmt.Sprintf("..%s.tmp-%d", filepath.Base(path), time.Now().UnixNano()))
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// writeFile atomically replaces path with content using a temporary file in the same directory,
+// the file is left untouched when its content and permissions are unchanged
+func writeFile(path string, content []byte, perm os.FileMode, logger *slog.Logger) (string, error) {
+	action := "update"
+	if info, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		action = "create"
+	} else if err != nil {
+		return "", err
+	} else if info.Mode().Perm() == perm {
+		if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, content) {
+			return "unchanged", nil
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		logger.Error("Unable to create directory", "dir", filepath.Dir(path), "err", err)
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), fmt.Sprintf(".%s.tmp-*", filepath.Base(path)))
+	if err != nil {
+		logger.Error("Unable to create temporary file", "path", path, "err", err)
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		logger.Error("Unable to write temporary file", "path", tmp.Name(), "err", err)
+		return "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		logger.Error("Unable to rename temporary file", "path", path, "err", err)
+		return "", err
+	}
+	return action, nil
+}
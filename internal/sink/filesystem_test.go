@@ -0,0 +1,170 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/common/promslog"
+)
+
+func TestWriteFilesystemJSON(t *testing.T) {
+	dir := t.TempDir()
+	data := map[string]string{"user1": "1000", "user2": "1001"}
+	action, err := WriteFilesystem(dir, FormatJSON, "user-uid-map", data, false, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if action != "create" {
+		t.Errorf("Unexpected action, got: %s", action)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "user-uid-map.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  \"user1\": \"1000\",\n  \"user2\": \"1001\"\n}\n"
+	if string(content) != expected {
+		t.Errorf("Unexpected content\nExpected: %s\nGot: %s", expected, string(content))
+	}
+	action, err = WriteFilesystem(dir, FormatJSON, "user-uid-map", data, false, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if action != "unchanged" {
+		t.Errorf("Unexpected action, got: %s", action)
+	}
+	data["user3"] = "1002"
+	action, err = WriteFilesystem(dir, FormatJSON, "user-uid-map", data, false, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if action != "update" {
+		t.Errorf("Unexpected action, got: %s", action)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Unexpected files left in %s: %v", dir, entries)
+	}
+}
+
+func TestWriteFilesystemConfigMap(t *testing.T) {
+	dir := t.TempDir()
+	data := map[string]string{"user1": "1000", "user2": "1001"}
+	action, err := WriteFilesystem(dir, FormatConfigMap, "user-home-map", data, true, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if action != "create" {
+		t.Errorf("Unexpected action, got: %s", action)
+	}
+	info, err := os.Stat(filepath.Join(dir, "user-home-map", "user1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Unexpected permissions, got: %v", info.Mode().Perm())
+	}
+	if target, err := os.Readlink(filepath.Join(dir, "user-home-map", "user1")); err != nil || target != filepath.Join("..data", "user1") {
+		t.Errorf("Expected user1 to be a symlink through ..data, got: %s %v", target, err)
+	}
+	previous, err := os.Readlink(filepath.Join(dir, "user-home-map", "..data"))
+	if err != nil {
+		t.Fatalf("Expected ..data symlink: %v", err)
+	}
+	delete(data, "user1")
+	action, err = WriteFilesystem(dir, FormatConfigMap, "user-home-map", data, true, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if action != "update" {
+		t.Errorf("Unexpected action, got: %s", action)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "user-home-map", "user1")); !os.IsNotExist(err) {
+		t.Errorf("Expected stale key user1 to be removed, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "user-home-map", previous)); !os.IsNotExist(err) {
+		t.Errorf("Expected previous data directory %s to be removed, got: %v", previous, err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "user-home-map", "user2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "1001" {
+		t.Errorf("Unexpected content for user2, got: %s", string(content))
+	}
+	action, err = WriteFilesystem(dir, FormatConfigMap, "user-home-map", data, true, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if action != "unchanged" {
+		t.Errorf("Unexpected action, got: %s", action)
+	}
+	if _, err := WriteFilesystem(dir, FormatConfigMap, "bad-map", map[string]string{"../foo": "bar"}, false, promslog.NewNopLogger()); err == nil {
+		t.Errorf("Expected error writing invalid key")
+	}
+}
+
+func TestWriteFilesystemConfigMapLayout(t *testing.T) {
+	dir := t.TempDir()
+	mapDir := filepath.Join(dir, "user-uid-map")
+	if err := os.MkdirAll(mapDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// Keys written as regular files are replaced with symlinks through ..data
+	if err := os.WriteFile(filepath.Join(mapDir, "user1"), []byte("999"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(mapDir, "user3"), []byte("1002"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]string{"user1": "1000", "user2": "1001"}
+	action, err := WriteFilesystem(dir, FormatConfigMap, "user-uid-map", data, false, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if action != "create" {
+		t.Errorf("Unexpected action, got: %s", action)
+	}
+	entries, err := os.ReadDir(mapDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	dataDirs := 0
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dataDirs++
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "..data,user1,user2" {
+		t.Errorf("Unexpected entries, got: %v", names)
+	}
+	if dataDirs != 1 {
+		t.Errorf("Unexpected number of timestamped directories, got: %d", dataDirs)
+	}
+	for key, value := range data {
+		content, err := os.ReadFile(filepath.Join(mapDir, key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != value {
+			t.Errorf("Unexpected content for %s, got: %s", key, string(content))
+		}
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	gitRemoteName = "origin"
+	// maxSummaryKeys is the number of added or removed keys listed per ConfigMap in commit messages
+	maxSummaryKeys = 10
+)
+
+// GitOptions configures the repository written to by the Git sink
+type GitOptions struct {
+	// Dir is the path of the local repository, it is cloned from Remote or initialized when missing
+	Dir string
+	// Path is the directory within the repository to write ConfigMaps to
+	Path string
+	// Remote is the optional URL to pull from and push to
+	Remote      string
+	Branch      string
+	Username    string
+	Password    string
+	AuthorName  string
+	AuthorEmail string
+}
+
+// ConfigMap is the ConfigMap of a mapper rendered by the Git sink
+type ConfigMap struct {
+	Name        string
+	Labels      map[string]string
+	Annotations map[string]string
+	Data        map[string]string
+}
+
+type configMapManifest struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   configMapMetadata `json:"metadata"`
+	Data       map[string]string `json:"data"`
+}
+
+type configMapMetadata struct {
+	Name        string            `json:"name"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// WriteGit renders each ConfigMap as YAML into the repository and commits when any changed,
+// the commit is pushed when a remote is configured. The action of each ConfigMap is returned
+// keyed by name, either create, update or unchanged.
+func WriteGit(opts GitOptions, configMaps []ConfigMap, logger *slog.Logger) (map[string]string, error) {
+	repo, err := openRepository(opts, logger)
+	if err != nil {
+		logger.Error("Unable to open Git repository", "dir", opts.Dir, "err", err)
+		return nil, err
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Remote != "" {
+		if err := resetToRemote(repo, worktree, opts); err != nil {
+			logger.Error("Unable to update Git repository from remote", "dir", opts.Dir, "remote", opts.Remote, "err", err)
+			return nil, err
+		}
+	}
+	sort.Slice(configMaps, func(i, j int) bool { return configMaps[i].Name < configMaps[j].Name })
+	actions := make(map[string]string)
+	summaries := []string{}
+	for _, cm := range configMaps {
+		path := filepath.Join(opts.Path, fmt.Sprintf("%s.yaml", cm.Name))
+		fullPath := filepath.Join(opts.Dir, path)
+		previous := make(map[string]string)
+		if content, err := os.ReadFile(fullPath); err == nil {
+			manifest := configMapManifest{}
+			if err := yaml.Unmarshal(content, &manifest); err == nil {
+				previous = manifest.Data
+			}
+		}
+		manifest := configMapManifest{
+			APIVersion: "v1",
+			Kind:       "ConfigMap",
+			Metadata: configMapMetadata{
+				Name:        cm.Name,
+				Labels:      cm.Labels,
+				Annotations: cm.Annotations,
+			},
+			Data: cm.Data,
+		}
+		content, err := yaml.Marshal(manifest)
+		if err != nil {
+			return nil, err
+		}
+		action, err := writeFile(fullPath, content, 0o644, logger)
+		if err != nil {
+			return nil, err
+		}
+		actions[cm.Name] = action
+		if action == "unchanged" {
+			continue
+		}
+		if _, err := worktree.Add(filepath.ToSlash(path)); err != nil {
+			logger.Error("Unable to add file to Git index", "path", path, "err", err)
+			return nil, err
+		}
+		summaries = append(summaries, summarizeKeys(cm.Name, previous, cm.Data))
+	}
+	if len(summaries) > 0 {
+		message := fmt.Sprintf("Update ConfigMaps from LDAP\n\n%s\n", strings.Join(summaries, "\n"))
+		hash, err := worktree.Commit(message, &git.CommitOptions{
+			Author: &object.Signature{
+				Name:  opts.AuthorName,
+				Email: opts.AuthorEmail,
+				When:  time.Now(),
+			},
+		})
+		if err != nil {
+			logger.Error("Unable to commit to Git repository", "dir", opts.Dir, "err", err)
+			return nil, err
+		}
+		logger.Info("Committed ConfigMaps to Git repository", "dir", opts.Dir, "commit", hash.String(), "configmaps", len(summaries))
+	}
+	if opts.Remote == "" {
+		return actions, nil
+	}
+	if _, err := repo.Head(); errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return actions, nil
+	}
+	branch := plumbing.NewBranchReferenceName(opts.Branch)
+	err = repo.Push(&git.PushOptions{
+		RemoteName: gitRemoteName,
+		RefSpecs:   []gitconfig.RefSpec{gitconfig.RefSpec(fmt.Sprintf("%s:%s", branch, branch))},
+		Auth:       gitAuth(opts),
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		logger.Error("Unable to push Git repository", "dir", opts.Dir, "remote", opts.Remote, "err", err)
+		return nil, err
+	}
+	return actions, nil
+}
+
+// resetToRemote fetches the branch from the remote and hard resets the worktree to it. Local commits are
+// discarded so a branch that diverged from the remote, such as after a rejected push, is rebuilt from the
+// ConfigMaps rendered this run instead of failing every run. Nothing is reset when the remote branch does not exist.
+func resetToRemote(repo *git.Repository, worktree *git.Worktree, opts GitOptions) error {
+	remoteBranch := plumbing.NewRemoteReferenceName(gitRemoteName, opts.Branch)
+	err := repo.Fetch(&git.FetchOptions{
+		RemoteName: gitRemoteName,
+		RefSpecs:   []gitconfig.RefSpec{gitconfig.RefSpec(fmt.Sprintf("+%s:%s", plumbing.NewBranchReferenceName(opts.Branch), remoteBranch))},
+		Auth:       gitAuth(opts),
+		Force:      true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) && !errors.Is(err, transport.ErrEmptyRemoteRepository) &&
+		!errors.Is(err, git.NoMatchingRefSpecError{}) {
+		return err
+	}
+	ref, err := repo.Reference(remoteBranch, true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return worktree.Reset(&git.ResetOptions{Commit: ref.Hash(), Mode: git.HardReset})
+}
+
+// openRepository opens the local repository, cloning it from the remote or initializing it when missing
+func openRepository(opts GitOptions, logger *slog.Logger) (*git.Repository, error) {
+	repo, err := git.PlainOpen(opts.Dir)
+	if !errors.Is(err, git.ErrRepositoryNotExists) {
+		return repo, err
+	}
+	branch := plumbing.NewBranchReferenceName(opts.Branch)
+	if opts.Remote != "" {
+		logger.Info("Cloning Git repository", "dir", opts.Dir, "remote", opts.Remote)
+		repo, err = git.PlainClone(opts.Dir, false, &git.CloneOptions{
+			URL:           opts.Remote,
+			RemoteName:    gitRemoteName,
+			ReferenceName: branch,
+			SingleBranch:  true,
+			Auth:          gitAuth(opts),
+		})
+		if !errors.Is(err, transport.ErrEmptyRemoteRepository) {
+			return repo, err
+		}
+	}
+	logger.Info("Initializing Git repository", "dir", opts.Dir)
+	repo, err = git.PlainInitWithOptions(opts.Dir, &git.PlainInitOptions{
+		InitOptions: git.InitOptions{DefaultBranch: branch},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if opts.Remote != "" {
+		_, err = repo.CreateRemote(&gitconfig.RemoteConfig{
+			Name: gitRemoteName,
+			URLs: []string{opts.Remote},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return repo, nil
+}
+
+// gitAuth returns HTTP basic authentication when a username or password is configured
+func gitAuth(opts GitOptions) transport.AuthMethod {
+	if opts.Username == "" && opts.Password == "" {
+		return nil
+	}
+	return &http.BasicAuth{Username: opts.Username, Password: opts.Password}
+}
+
+// summarizeKeys describes the keys added, removed and changed in a ConfigMap for commit messages
+func summarizeKeys(name string, previous map[string]string, data map[string]string) string {
+	added := []string{}
+	removed := []string{}
+	changed := 0
+	for key, value := range data {
+		if previousValue, ok := previous[key]; !ok {
+			added = append(added, key)
+		} else if previousValue != value {
+			changed++
+		}
+	}
+	for key := range previous {
+		if _, ok := data[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	parts := []string{}
+	if len(added) > 0 {
+		parts = append(parts, fmt.Sprintf("added %s", listKeys(added)))
+	}
+	if len(removed) > 0 {
+		parts = append(parts, fmt.Sprintf("removed %s", listKeys(removed)))
+	}
+	if changed > 0 {
+		parts = append(parts, fmt.Sprintf("changed %d keys", changed))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "updated metadata")
+	}
+	return fmt.Sprintf("%s: %s", name, strings.Join(parts, "; "))
+}
+
+// listKeys returns the sorted keys separated by commas, limited to maxSummaryKeys
+func listKeys(keys []string) string {
+	sort.Strings(keys)
+	if len(keys) <= maxSummaryKeys {
+		return strings.Join(keys, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(keys[:maxSummaryKeys], ", "), len(keys)-maxSummaryKeys)
+}
@@ -0,0 +1,202 @@
+// Copyright 2020 Ohio Supercomputer Center
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/prometheus/common/promslog"
+	"sigs.k8s.io/yaml"
+)
+
+func TestWriteGit(t *testing.T) {
+	remoteDir := t.TempDir()
+	remote, err := git.PlainInit(remoteDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := GitOptions{
+		Dir:         filepath.Join(t.TempDir(), "repo"),
+		Path:        "ldap",
+		Remote:      remoteDir,
+		Branch:      "main",
+		AuthorName:  "k8-ldap-configmap",
+		AuthorEmail: "k8-ldap-configmap@localhost",
+	}
+	configMaps := []ConfigMap{
+		{
+			Name:   "user-uid-map",
+			Labels: map[string]string{"app.kubernetes.io/managed-by": "k8-ldap-configmap"},
+			Data:   map[string]string{"user1": "1000", "user2": "1001"},
+		},
+	}
+	actions, err := WriteGit(opts, configMaps, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if actions["user-uid-map"] != "create" {
+		t.Errorf("Unexpected action, got: %s", actions["user-uid-map"])
+	}
+	commit := remoteCommit(t, remote)
+	if !strings.Contains(commit.Message, "user-uid-map: added user1, user2") {
+		t.Errorf("Unexpected commit message, got: %s", commit.Message)
+	}
+	file, err := commit.File("ldap/user-uid-map.yaml")
+	if err != nil {
+		t.Fatalf("Unexpected error getting file: %v", err)
+	}
+	content, _ := file.Contents()
+	manifest := configMapManifest{}
+	if err := yaml.Unmarshal([]byte(content), &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Kind != "ConfigMap" || manifest.Metadata.Name != "user-uid-map" || manifest.Data["user2"] != "1001" {
+		t.Errorf("Unexpected manifest, got: %s", content)
+	}
+
+	actions, err = WriteGit(opts, configMaps, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if actions["user-uid-map"] != "unchanged" {
+		t.Errorf("Unexpected action, got: %s", actions["user-uid-map"])
+	}
+	if hash := remoteCommit(t, remote).Hash; hash != commit.Hash {
+		t.Errorf("Expected no commit when unchanged, got: %s", hash)
+	}
+
+	configMaps[0].Data = map[string]string{"user1": "2000", "user3": "1002"}
+	actions, err = WriteGit(opts, configMaps, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if actions["user-uid-map"] != "update" {
+		t.Errorf("Unexpected action, got: %s", actions["user-uid-map"])
+	}
+	commit = remoteCommit(t, remote)
+	if !strings.Contains(commit.Message, "user-uid-map: added user3; removed user2; changed 1 keys") {
+		t.Errorf("Unexpected commit message, got: %s", commit.Message)
+	}
+	if commit.NumParents() != 1 {
+		t.Errorf("Unexpected number of parents, got: %d", commit.NumParents())
+	}
+
+	clone := GitOptions{
+		Dir:    filepath.Join(t.TempDir(), "clone"),
+		Path:   "ldap",
+		Remote: remoteDir,
+		Branch: "main",
+	}
+	actions, err = WriteGit(clone, configMaps, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if actions["user-uid-map"] != "unchanged" {
+		t.Errorf("Unexpected action after clone, got: %s", actions["user-uid-map"])
+	}
+}
+
+func TestWriteGitRemoteAdvanced(t *testing.T) {
+	remoteDir := t.TempDir()
+	remote, err := git.PlainInit(remoteDir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := GitOptions{
+		Dir:         filepath.Join(t.TempDir(), "repo"),
+		Path:        "ldap",
+		Remote:      remoteDir,
+		Branch:      "main",
+		AuthorName:  "k8-ldap-configmap",
+		AuthorEmail: "k8-ldap-configmap@localhost",
+	}
+	configMaps := []ConfigMap{{Name: "user-uid-map", Data: map[string]string{"user1": "1000"}}}
+	if _, err := WriteGit(opts, configMaps, promslog.NewNopLogger()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Commit locally without pushing so the local branch diverges once the remote advances
+	local := opts
+	local.Remote = ""
+	configMaps[0].Data = map[string]string{"user1": "1001"}
+	if _, err := WriteGit(local, configMaps, promslog.NewNopLogger()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	other := GitOptions{
+		Dir:         filepath.Join(t.TempDir(), "other"),
+		Path:        "ldap",
+		Remote:      remoteDir,
+		Branch:      "main",
+		AuthorName:  "other",
+		AuthorEmail: "other@localhost",
+	}
+	if _, err := WriteGit(other, []ConfigMap{{Name: "user-gid-map", Data: map[string]string{"user1": "100"}}}, promslog.NewNopLogger()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	advanced := remoteCommit(t, remote)
+
+	configMaps[0].Data = map[string]string{"user1": "1002"}
+	actions, err := WriteGit(opts, configMaps, promslog.NewNopLogger())
+	if err != nil {
+		t.Fatalf("Unexpected error after remote advanced: %v", err)
+	}
+	if actions["user-uid-map"] != "update" {
+		t.Errorf("Unexpected action, got: %s", actions["user-uid-map"])
+	}
+	commit := remoteCommit(t, remote)
+	if commit.NumParents() != 1 || commit.ParentHashes[0] != advanced.Hash {
+		t.Errorf("Expected commit on top of the advanced remote, got parents: %v", commit.ParentHashes)
+	}
+	if !strings.Contains(commit.Message, "user-uid-map: changed 1 keys") {
+		t.Errorf("Unexpected commit message, got: %s", commit.Message)
+	}
+	for _, name := range []string{"ldap/user-uid-map.yaml", "ldap/user-gid-map.yaml"} {
+		if _, err := commit.File(name); err != nil {
+			t.Errorf("Unexpected error getting %s: %v", name, err)
+		}
+	}
+}
+
+func TestSummarizeKeys(t *testing.T) {
+	previous := map[string]string{}
+	data := map[string]string{}
+	for i := 0; i < 12; i++ {
+		data[fmt.Sprintf("user%02d", i)] = "1"
+	}
+	expected := "user-uid-map: added user00, user01, user02, user03, user04, user05, user06, user07, user08, user09 and 2 more"
+	if val := summarizeKeys("user-uid-map", previous, data); val != expected {
+		t.Errorf("Unexpected summary\nExpected: %s\nGot: %s", expected, val)
+	}
+	if val := summarizeKeys("user-uid-map", data, data); val != "user-uid-map: updated metadata" {
+		t.Errorf("Unexpected summary, got: %s", val)
+	}
+}
+
+func remoteCommit(t *testing.T, remote *git.Repository) *object.Commit {
+	ref, err := remote.Reference(plumbing.NewBranchReferenceName("main"), true)
+	if err != nil {
+		t.Fatalf("Unexpected error getting remote branch: %v", err)
+	}
+	commit, err := remote.CommitObject(ref.Hash())
+	if err != nil {
+		t.Fatalf("Unexpected error getting remote commit: %v", err)
+	}
+	return commit
+}
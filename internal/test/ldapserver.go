@@ -33,6 +33,12 @@ const (
 	GroupFilterStatus = "(&(objectClass=posixGroup)(status=ACTIVE))"
 	UserFilter        = "(objectClass=posixAccount)"
 	UserFilterStatus  = "(&(objectClass=posixAccount)(status=ACTIVE))"
+	NetgroupBaseDN    = "ou=Netgroup,dc=test"
+	NetgroupFilter    = "(objectClass=nisNetgroup)"
+	SudoersBaseDN     = "ou=SUDOers,dc=test"
+	SudoersFilter     = "(objectClass=sudoRole)"
+	AutomountBaseDN   = "ou=Automount,dc=test"
+	AutomountFilter   = "(objectClass=automount)"
 )
 
 // GENCERTS: openssl req -newkey rsa:2048 -x509 -sha256 -days 3650 -nodes -out test.out -keyout test.key -subj "/C=US/ST=Ohio/L=Columbus/O=OSC/OU=OSC/CN=127.0.0.1"
@@ -119,6 +125,22 @@ func LdapServer() *ldap.Server {
 		BaseDn(UserBaseDN).
 		Filter(UserFilterStatus).
 		Label("SEARCH - USER")
+	routes.Search(handleSearchNetgroup).
+		BaseDn(NetgroupBaseDN).
+		Filter(NetgroupFilter).
+		Label("SEARCH - NETGROUP")
+	routes.Search(handleSearchSudoers).
+		BaseDn(SudoersBaseDN).
+		Filter(SudoersFilter).
+		Label("SEARCH - SUDOERS")
+	routes.Search(handleSearchAutomount).
+		BaseDn(fmt.Sprintf("automountMapName=auto.home,%s", AutomountBaseDN)).
+		Filter(AutomountFilter).
+		Label("SEARCH - AUTOMOUNT")
+	routes.Search(handleSearchAutomount).
+		BaseDn(fmt.Sprintf("automountMapName=auto.project,%s", AutomountBaseDN)).
+		Filter(AutomountFilter).
+		Label("SEARCH - AUTOMOUNT")
 	//routes.Search(handleSearch).Label("SEARCH - NO MATCH")
 	routes.Extended(handleStartTLS).RequestName(ldap.NoticeOfStartTLS).Label("StartTLS")
 	server.Handle(routes)
@@ -283,6 +305,99 @@ func handleSearchUser(w ldap.ResponseWriter, m *ldap.Message) {
 	w.Write(res)
 }
 
+func handleSearchNetgroup(w ldap.ResponseWriter, m *ldap.Message) {
+	r := m.GetSearchRequest()
+	data := map[string]map[string][]string{
+		"netgroup1": {
+			"objectClass":       []string{"nisNetgroup"},
+			"nisNetgroupTriple": []string{"(,testuser1,)", "(host1,testuser2,test)"},
+			"memberNisNetgroup": []string{"netgroup2"},
+		},
+		"netgroup2": {
+			"objectClass":       []string{"nisNetgroup"},
+			"nisNetgroupTriple": []string{"(-,testuser3,)"},
+			"memberNisNetgroup": []string{"netgroup1"},
+		},
+		"netgroup3": {
+			"objectClass":       []string{"nisNetgroup"},
+			"nisNetgroupTriple": []string{"(host1,,)", "(host2,-,)"},
+		},
+	}
+	for cn, attrs := range data {
+		dn := fmt.Sprintf("cn=%s,%s", cn, r.BaseObject())
+		e := ldap.NewSearchResultEntry(dn)
+		e.AddAttribute("cn", message.AttributeValue(cn))
+		for key, value := range attrs {
+			values := []message.AttributeValue{}
+			for _, v := range value {
+				values = append(values, message.AttributeValue(v))
+			}
+			e.AddAttribute(message.AttributeDescription(key), values...)
+		}
+		w.Write(e)
+	}
+	res := ldap.NewSearchResultDoneResponse(ldap.LDAPResultSuccess)
+	w.Write(res)
+}
+
+func handleSearchSudoers(w ldap.ResponseWriter, m *ldap.Message) {
+	r := m.GetSearchRequest()
+	data := map[string]map[string][]string{
+		"debug": {
+			"objectClass":   []string{"sudoRole"},
+			"sudoUser":      []string{"testuser1", "%testgroup1"},
+			"sudoHost":      []string{"ALL"},
+			"sudoCommand":   []string{"/usr/bin/kubectl debug"},
+			"sudoRunAsUser": []string{"root"},
+		},
+		"restart": {
+			"objectClass": []string{"sudoRole"},
+			"sudoUser":    []string{"#1002"},
+			"sudoHost":    []string{"host1", "host2"},
+			"sudoCommand": []string{"/usr/bin/systemctl restart", "/usr/bin/systemctl status"},
+		},
+	}
+	for cn, attrs := range data {
+		dn := fmt.Sprintf("cn=%s,%s", cn, r.BaseObject())
+		e := ldap.NewSearchResultEntry(dn)
+		e.AddAttribute("cn", message.AttributeValue(cn))
+		for key, value := range attrs {
+			values := []message.AttributeValue{}
+			for _, v := range value {
+				values = append(values, message.AttributeValue(v))
+			}
+			e.AddAttribute(message.AttributeDescription(key), values...)
+		}
+		w.Write(e)
+	}
+	res := ldap.NewSearchResultDoneResponse(ldap.LDAPResultSuccess)
+	w.Write(res)
+}
+
+func handleSearchAutomount(w ldap.ResponseWriter, m *ldap.Message) {
+	r := m.GetSearchRequest()
+	data := map[string]map[string]string{
+		fmt.Sprintf("automountMapName=auto.home,%s", AutomountBaseDN): {
+			"*":         "-rw,hard nfs1:/home/&",
+			"testuser2": "-rw nfs2:/export/home/testuser2",
+		},
+		fmt.Sprintf("automountMapName=auto.project,%s", AutomountBaseDN): {
+			"project1": "-fstype=nfs4 nfs3:/project/project1",
+			"project2": "nfs3:/project/project2",
+		},
+	}
+	for key, info := range data[string(r.BaseObject())] {
+		dn := fmt.Sprintf("automountKey=%s,%s", key, r.BaseObject())
+		e := ldap.NewSearchResultEntry(dn)
+		e.AddAttribute("objectClass", message.AttributeValue("automount"))
+		e.AddAttribute("automountKey", message.AttributeValue(key))
+		e.AddAttribute("automountInformation", message.AttributeValue(info))
+		w.Write(e)
+	}
+	res := ldap.NewSearchResultDoneResponse(ldap.LDAPResultSuccess)
+	w.Write(res)
+}
+
 /*func handleSearch(w ldap.ResponseWriter, m *ldap.Message) {
 	res := ldap.NewSearchResultDoneResponse(ldap.LDAPResultNoSuchObject)
 	w.Write(res)